@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ollybritton/StupidChess/engines"
+	"github.com/ollybritton/StupidChess/epd"
+	"github.com/ollybritton/StupidChess/position"
+	"github.com/ollybritton/StupidChess/search"
+	"github.com/ollybritton/StupidChess/uci"
+	"github.com/spf13/cobra"
+)
+
+// epdCmd represents the epd command
+var epdCmd = &cobra.Command{
+	Use:   "epd <file>",
+	Short: "run an engine over a suite of EPD test positions",
+	Long: `Runs an engine over every position in an EPD test suite such as WAC or STS, checking whether the move it
+plays is one of the best moves ("bm") and isn't one of the moves to avoid ("am").
+
+Positions with a list of moves and points in their "c0" comment, like those in STS, are scored using that list.
+Otherwise a position scores one point if it is solved.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		engineName := getEngine(cmd)
+		if _, ok := engines.EngineInfo[engineName]; !ok {
+			return fmt.Errorf("engine %s not found", engineName)
+		}
+
+		moveTime, err := cmd.Flags().GetDuration("movetime")
+		if err != nil {
+			return fmt.Errorf("error determining move time: %w", err)
+		}
+
+		depth, err := cmd.Flags().GetUint("depth")
+		if err != nil {
+			return fmt.Errorf("error determining depth: %w", err)
+		}
+
+		verbose, err := cmd.Flags().GetBool("verbose")
+		if err != nil {
+			return fmt.Errorf("error determining verbosity: %w", err)
+		}
+
+		engineOptions, err := cmd.Flags().GetStringArray("option")
+		if err != nil {
+			return fmt.Errorf("error determining engine options: %w", err)
+		}
+
+		f, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("couldn't open epd file: %w", err)
+		}
+
+		records, err := epd.ReadAll(f)
+		f.Close()
+
+		if err != nil {
+			return fmt.Errorf("couldn't read epd file: %w", err)
+		}
+
+		// The engine is run as a separate process since engines print their output rather than returning it.
+		executable, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("couldn't find stupidchess binary: %w", err)
+		}
+
+		session, err := uci.NewGUISessionFromBinary(executable, "uci", "--engine", engineName)
+		if err != nil {
+			return err
+		}
+
+		if !verbose {
+			session.SetOutput(io.Discard)
+		}
+
+		session.Open()
+		defer session.Close()
+
+		for _, option := range engineOptions {
+			name, value, _ := strings.Cut(option, "=")
+			session.SetOption(name, value)
+		}
+
+		options := search.NewDeafultOptions()
+		options.MoveTime = moveTime
+
+		if depth != 0 {
+			options.Depth = depth
+
+			// Only limit the time as well as the depth if asked to.
+			if !cmd.Flags().Changed("movetime") {
+				options.MoveTime = 0
+			}
+		}
+
+		results := runEPD(session, records, options)
+
+		fmt.Printf(
+			"\nsolved %d/%d (%.1f%%), failed %d, score %d/%d\n",
+			results.solved,
+			len(records),
+			100*float64(results.solved)/float64(len(records)),
+			len(records)-results.solved,
+			results.points,
+			results.maxPoints,
+		)
+
+		return nil
+	},
+}
+
+// epdResults are the totals from running an engine over an EPD suite.
+type epdResults struct {
+	solved    int
+	points    int
+	maxPoints int
+}
+
+// runEPD asks the engine for a move in every position and prints whether each one was solved.
+func runEPD(session *uci.GUISession, records []*epd.EPD, options search.SearchOptions) epdResults {
+	var results epdResults
+
+	for i, record := range records {
+		session.NewGame()
+		session.LoadPosition(record.Position)
+
+		start := time.Now()
+
+		move, err := session.Go(options)
+		if err != nil {
+			fmt.Printf("%4d/%d %-12s error: %v\n", i+1, len(records), record.ID(), err)
+			continue
+		}
+
+		solved, err := record.Solved(move)
+		if err != nil {
+			fmt.Printf("%4d/%d %-12s error: %v\n", i+1, len(records), record.ID(), err)
+			continue
+		}
+
+		points, maxPoints, err := record.Score(move)
+		if err != nil {
+			fmt.Printf("%4d/%d %-12s error: %v\n", i+1, len(records), record.ID(), err)
+			continue
+		}
+
+		status := "failed"
+		if solved {
+			status = "solved"
+			results.solved++
+		}
+
+		results.points += points
+		results.maxPoints += maxPoints
+
+		fmt.Printf(
+			"%4d/%d %-12s %s %-8s %s (%d/%d points, %s)\n",
+			i+1,
+			len(records),
+			record.ID(),
+			status,
+			record.Position.StringSAN(move),
+			expectedMoves(record),
+			points,
+			maxPoints,
+			time.Since(start).Round(time.Millisecond),
+		)
+	}
+
+	return results
+}
+
+// expectedMoves describes the best moves and moves to avoid in a record, e.g. "bm Qg6 am Qxg7".
+func expectedMoves(record *epd.EPD) string {
+	var fields []string
+
+	bestMoves, _ := record.BestMoves()
+	if len(bestMoves) != 0 {
+		fields = append(fields, "bm", sanMoves(record.Position, bestMoves))
+	}
+
+	avoidMoves, _ := record.AvoidMoves()
+	if len(avoidMoves) != 0 {
+		fields = append(fields, "am", sanMoves(record.Position, avoidMoves))
+	}
+
+	return strings.Join(fields, " ")
+}
+
+func sanMoves(pos *position.Position, moves []position.Move) string {
+	sans := make([]string, 0, len(moves))
+	for _, move := range moves {
+		sans = append(sans, pos.StringSAN(move))
+	}
+
+	return strings.Join(sans, " ")
+}
+
+func init() {
+	rootCmd.AddCommand(epdCmd)
+
+	epdCmd.Flags().Duration("movetime", time.Second, "time to spend on each position")
+	epdCmd.Flags().Uint("depth", 0, "depth to search each position to, or 0 for no limit")
+	epdCmd.Flags().BoolP("verbose", "v", false, "print output from the engine")
+	epdCmd.Flags().StringArray("option", nil, "engine option to set in the form name=value, can be repeated")
+}
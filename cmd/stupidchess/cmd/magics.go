@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ollybritton/StupidChess/position"
+	"github.com/spf13/cobra"
+)
+
+// magicsCmd represents the magics command
+var magicsCmd = &cobra.Command{
+	Use:   "magics",
+	Short: "search for the magic numbers used to generate rook and bishop moves",
+	Long: `Searches for magic numbers for every square, checks them against the slow way of generating rook and bishop
+moves, and writes them out as the Go source for position/magic.go.
+
+The tables of moves have 2^--rook-bits and 2^--bishop-bits entries for every square, so only smaller --rook-bits and
+--bishop-bits make them smaller. Going below the 12 and 9 bits needed for the most crowded squares relies on finding
+numbers that give the same index for blockers with the same moves, which can take a very large number of --tries or
+be impossible.
+
+With --verify, the magic numbers currently in use are checked instead.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		verify, err := cmd.Flags().GetBool("verify")
+		if err != nil {
+			return fmt.Errorf("error determining verify mode: %w", err)
+		}
+
+		if verify {
+			magics := position.CurrentMagics()
+			if err := magics.Verify(); err != nil {
+				return fmt.Errorf("magic numbers are wrong: %w", err)
+			}
+
+			fmt.Printf("magic numbers are correct, tables use %dKB\n", magics.TableSize()/1024)
+			return nil
+		}
+
+		options, err := getMagicOptions(cmd)
+		if err != nil {
+			return err
+		}
+
+		out, err := cmd.Flags().GetString("out")
+		if err != nil {
+			return fmt.Errorf("error determining output file: %w", err)
+		}
+
+		start := time.Now()
+
+		magics, err := position.FindMagics(options)
+		if err != nil {
+			return fmt.Errorf("couldn't find magic numbers: %w", err)
+		}
+
+		if err := magics.Verify(); err != nil {
+			return fmt.Errorf("magic numbers found are wrong: %w", err)
+		}
+
+		duration := time.Since(start)
+
+		var source bytes.Buffer
+		if err := magics.WriteSource(&source); err != nil {
+			return err
+		}
+
+		// Standard output only gets the source, so that it can be redirected to a file.
+		if out == "" {
+			fmt.Print(source.String())
+			return nil
+		}
+
+		if err := os.WriteFile(out, source.Bytes(), 0o644); err != nil {
+			return fmt.Errorf("couldn't write magic numbers: %w", err)
+		}
+
+		fmt.Printf("found magic numbers in %s, tables use %dKB\n", duration.Round(time.Millisecond), magics.TableSize()/1024)
+
+		return nil
+	},
+}
+
+// getMagicOptions returns the options for searching for magic numbers given by the command's flags.
+func getMagicOptions(cmd *cobra.Command) (position.MagicOptions, error) {
+	rookBits, err := cmd.Flags().GetInt("rook-bits")
+	if err != nil {
+		return position.MagicOptions{}, fmt.Errorf("error determining rook bits: %w", err)
+	}
+
+	bishopBits, err := cmd.Flags().GetInt("bishop-bits")
+	if err != nil {
+		return position.MagicOptions{}, fmt.Errorf("error determining bishop bits: %w", err)
+	}
+
+	tries, err := cmd.Flags().GetInt("tries")
+	if err != nil {
+		return position.MagicOptions{}, fmt.Errorf("error determining tries: %w", err)
+	}
+
+	seed, err := cmd.Flags().GetInt64("seed")
+	if err != nil {
+		return position.MagicOptions{}, fmt.Errorf("error determining seed: %w", err)
+	}
+
+	return position.MagicOptions{
+		RookBits:   rookBits,
+		BishopBits: bishopBits,
+		Tries:      tries,
+		Seed:       seed,
+	}, nil
+}
+
+func init() {
+	rootCmd.AddCommand(magicsCmd)
+
+	magicsCmd.Flags().Bool("verify", false, "check the magic numbers currently in use rather than searching for new ones")
+	magicsCmd.Flags().Int("rook-bits", position.DefaultMagicOptions.RookBits, "largest number of bits in an index into the table of rook moves")
+	magicsCmd.Flags().Int("bishop-bits", position.DefaultMagicOptions.BishopBits, "largest number of bits in an index into the table of bishop moves")
+	magicsCmd.Flags().Int("tries", position.DefaultMagicOptions.Tries, "number of random numbers to try for each square before giving up")
+	magicsCmd.Flags().Int64("seed", position.DefaultMagicOptions.Seed, "seed for the random numbers tried")
+	magicsCmd.Flags().String("out", "", "file to write the Go source to, or standard output if empty")
+}
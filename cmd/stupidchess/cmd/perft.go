@@ -0,0 +1,255 @@
+package cmd
+
+import (
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ollybritton/StupidChess/epd"
+	"github.com/ollybritton/StupidChess/position"
+	"github.com/spf13/cobra"
+)
+
+// defaultPerftSuite is the suite of positions used when no EPD file is given.
+//
+//go:embed perftsuite.epd
+var defaultPerftSuite string
+
+// perftCmd represents the perft command
+var perftCmd = &cobra.Command{
+	Use:   "perft [file]",
+	Short: "check the move generator against a suite of perft results",
+	Long: `Counts the number of possible games from each position in an EPD perft suite, checking the counts against
+the "D1" to "D6" operations. If no file is given, the standard positions from the Chess Programming Wiki are used.
+
+With --fen, a single position is counted instead. With --extended, the counts are broken down by the kind of move made
+last, which helps to track down bugs in the move generator. With --divide as well as --fen, the counts at the final
+depth are also broken down by the first move.
+
+The moves at the root are split between --threads goroutines. Bulk counting and the hash table make counting much
+faster but can be turned off with --bulk=false and --hash 0 when checking the move generator itself.`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		depth, err := cmd.Flags().GetUint("depth")
+		if err != nil {
+			return fmt.Errorf("error determining depth: %w", err)
+		}
+
+		extended, err := cmd.Flags().GetBool("extended")
+		if err != nil {
+			return fmt.Errorf("error determining extended mode: %w", err)
+		}
+
+		fen, err := cmd.Flags().GetString("fen")
+		if err != nil {
+			return fmt.Errorf("error determining fen: %w", err)
+		}
+
+		divide, err := cmd.Flags().GetBool("divide")
+		if err != nil {
+			return fmt.Errorf("error determining divide mode: %w", err)
+		}
+
+		options, err := getPerftOptions(cmd)
+		if err != nil {
+			return err
+		}
+
+		options.Extended = extended
+
+		if fen != "" {
+			pos, err := position.NewPositionFromFEN(fen)
+			if err != nil {
+				return err
+			}
+
+			if depth == 0 {
+				return fmt.Errorf("need a depth to count a single position to")
+			}
+
+			for d := uint(1); d <= depth; d++ {
+				stats, duration := runPerft(pos, d, options)
+				printPerft(d, stats, duration, extended)
+			}
+
+			if divide {
+				printDivide(pos, depth, options)
+			}
+
+			return nil
+		}
+
+		var suite io.Reader = strings.NewReader(defaultPerftSuite)
+
+		if len(args) == 1 {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("couldn't open epd file: %w", err)
+			}
+			defer f.Close()
+
+			suite = f
+		}
+
+		records, err := epd.ReadAll(suite)
+		if err != nil {
+			return fmt.Errorf("couldn't read epd file: %w", err)
+		}
+
+		if !runPerftSuite(records, depth, options) {
+			return fmt.Errorf("not every perft count was right")
+		}
+
+		return nil
+	},
+}
+
+// runPerftSuite checks every count in the suite up to the given depth, or every count if the depth is 0. It returns
+// true if they all passed.
+func runPerftSuite(records []*epd.EPD, maxDepth uint, options position.PerftOptions) bool {
+	passed, total := 0, 0
+
+	var totalNodes uint
+	var totalDuration time.Duration
+
+	for _, record := range records {
+		name := record.ID()
+		if name == "" {
+			name = record.Position.StringFEN()
+		}
+
+		counts, err := record.PerftCounts()
+		if err != nil {
+			fmt.Println(err)
+			total++
+			continue
+		}
+
+		depths := make([]uint, 0, len(counts))
+		for depth := range counts {
+			if maxDepth == 0 || depth <= maxDepth {
+				depths = append(depths, depth)
+			}
+		}
+
+		sort.Slice(depths, func(i, j int) bool { return depths[i] < depths[j] })
+
+		for _, depth := range depths {
+			stats, duration := runPerft(record.Position, depth, options)
+			expected := counts[depth]
+
+			total++
+			totalNodes += stats.Nodes
+			totalDuration += duration
+
+			if uint64(stats.Nodes) == expected {
+				passed++
+				fmt.Printf("pass %-20s depth %d: %d nodes (%s)\n", name, depth, stats.Nodes, speed(stats.Nodes, duration))
+			} else {
+				fmt.Printf("FAIL %-20s depth %d: expected %d nodes, got %d\n", name, depth, expected, stats.Nodes)
+			}
+
+			if options.Extended {
+				fmt.Printf("     %s\n", stats)
+			}
+		}
+	}
+
+	fmt.Printf("\npassed %d/%d\n", passed, total)
+	fmt.Printf("%d nodes in %s (%s)\n", totalNodes, totalDuration.Round(time.Millisecond), speed(totalNodes, totalDuration))
+
+	return passed == total
+}
+
+// runPerft counts the games from the position to the given depth.
+func runPerft(pos *position.Position, depth uint, options position.PerftOptions) (position.PerftStats, time.Duration) {
+	start := time.Now()
+	stats := pos.PerftParallel(depth, options)
+
+	return stats, time.Since(start)
+}
+
+func printPerft(depth uint, stats position.PerftStats, duration time.Duration, extended bool) {
+	if extended {
+		fmt.Printf("depth %d: %s (%s)\n", depth, stats, speed(stats.Nodes, duration))
+	} else {
+		fmt.Printf("depth %d: %d nodes (%s)\n", depth, stats.Nodes, speed(stats.Nodes, duration))
+	}
+}
+
+// printDivide prints the number of games after each move in the position, in the same format as Position.Divide.
+func printDivide(pos *position.Position, depth uint, options position.PerftOptions) {
+	start := time.Now()
+	moves, results := pos.PerftDivide(depth, options)
+	duration := time.Since(start)
+
+	var total position.PerftStats
+
+	fmt.Println()
+
+	for i, move := range moves {
+		if options.Extended {
+			fmt.Printf("%s: %s\n", move, results[i])
+		} else {
+			fmt.Printf("%s: %d\n", move, results[i].Nodes)
+		}
+
+		total.Add(results[i])
+	}
+
+	fmt.Println()
+	fmt.Println("total:", total.Nodes)
+	fmt.Printf("speed: %s\n", speed(total.Nodes, duration))
+}
+
+// getPerftOptions returns the options for counting games given by the command's flags.
+func getPerftOptions(cmd *cobra.Command) (position.PerftOptions, error) {
+	threads, err := cmd.Flags().GetInt("threads")
+	if err != nil {
+		return position.PerftOptions{}, fmt.Errorf("error determining threads: %w", err)
+	}
+
+	bulk, err := cmd.Flags().GetBool("bulk")
+	if err != nil {
+		return position.PerftOptions{}, fmt.Errorf("error determining bulk counting: %w", err)
+	}
+
+	hashSize, err := cmd.Flags().GetInt("hash")
+	if err != nil {
+		return position.PerftOptions{}, fmt.Errorf("error determining hash size: %w", err)
+	}
+
+	options := position.PerftOptions{
+		Threads: threads,
+		Bulk:    bulk,
+	}
+
+	if hashSize > 0 {
+		options.Hash = position.NewPerftHash(hashSize)
+	}
+
+	return options, nil
+}
+
+// speed returns the number of nodes counted per second, in the same format as Position.Divide.
+func speed(nodes uint, duration time.Duration) string {
+	return fmt.Sprintf("%.2fkn/s", float64(nodes)/1000/duration.Seconds())
+}
+
+func init() {
+	rootCmd.AddCommand(perftCmd)
+
+	perftCmd.Flags().Uint("depth", 4, "largest depth to check, or 0 to check every depth in the suite")
+	perftCmd.Flags().Bool("extended", false, "break counts down by captures, en passant, castles, promotions, checks and checkmates")
+	perftCmd.Flags().String("fen", "", "count a single position instead of a suite")
+	perftCmd.Flags().Bool("divide", false, "break the count for a single position down by the first move")
+	perftCmd.Flags().Int("threads", runtime.NumCPU(), "number of goroutines to split the moves at the root between")
+	perftCmd.Flags().Bool("bulk", true, "count the legal moves at the last ply rather than making each one")
+	perftCmd.Flags().Int("hash", 16, "size of the hash table in megabytes, or 0 to disable it")
+}
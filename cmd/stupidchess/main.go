@@ -50,7 +50,12 @@ func logOutput() func() {
 
 func main() {
 	fn := logOutput()
-	defer fn()
+	err := cmd.Execute()
 
-	cmd.Execute()
+	// os.Exit doesn't run deferred functions, so the output has to be flushed first or an error would never be shown.
+	fn()
+
+	if err != nil {
+		os.Exit(1)
+	}
 }
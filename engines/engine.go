@@ -13,4 +13,7 @@ type Engine interface {
 	Prepare() error
 	Go(*position.Position, search.SearchOptions) error
 	Stop()
+
+	Options() []search.Option
+	SetOption(name, value string) error
 }
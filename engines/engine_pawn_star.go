@@ -46,6 +46,7 @@ func (e *EnginePawnStar) Prepare() error {
 }
 
 func (e *EnginePawnStar) NewGame() error {
+	e.searcher.NewGame()
 	return nil
 }
 
@@ -58,3 +59,11 @@ func (e *EnginePawnStar) Go(pos *position.Position, options search.SearchOptions
 func (e *EnginePawnStar) Stop() {
 	e.searcher.Stop()
 }
+
+func (e *EnginePawnStar) Options() []search.Option {
+	return e.searcher.Options()
+}
+
+func (e *EnginePawnStar) SetOption(name, value string) error {
+	return e.searcher.SetOption(name, value)
+}
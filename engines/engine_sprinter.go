@@ -34,6 +34,11 @@ func (e *EngineSprinter) Prepare() error {
 }
 
 func (e *EngineSprinter) Go(pos *position.Position, searchOptions search.SearchOptions) error {
+	if reason := pos.Outcome().Reason; reason == position.ReasonCheckmate || reason == position.ReasonStalemate {
+		fmt.Println("bestmove 0000")
+		return nil
+	}
+
 	legalMoves := pos.MovesLegal()
 
 	newMoves := pos.MovesLegal().Copy()
@@ -97,3 +102,11 @@ func (e *EngineSprinter) Go(pos *position.Position, searchOptions search.SearchO
 }
 
 func (e *EngineSprinter) Stop() {}
+
+func (e *EngineSprinter) Options() []search.Option {
+	return nil
+}
+
+func (e *EngineSprinter) SetOption(name, value string) error {
+	return fmt.Errorf("engine %s has no option %q", e.Name(), name)
+}
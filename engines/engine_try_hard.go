@@ -46,6 +46,7 @@ func (e *EngineTryHard) Prepare() error {
 }
 
 func (e *EngineTryHard) NewGame() error {
+	e.searcher.NewGame()
 	return nil
 }
 
@@ -58,3 +59,11 @@ func (e *EngineTryHard) Go(pos *position.Position, options search.SearchOptions)
 func (e *EngineTryHard) Stop() {
 	e.searcher.Stop()
 }
+
+func (e *EngineTryHard) Options() []search.Option {
+	return e.searcher.Options()
+}
+
+func (e *EngineTryHard) SetOption(name, value string) error {
+	return e.searcher.SetOption(name, value)
+}
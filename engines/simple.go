@@ -31,7 +31,19 @@ func (e *SimpleEngine) Prepare() error { return nil }
 func (e *SimpleEngine) NewGame() error { return nil }
 func (e *SimpleEngine) Stop()          {}
 
+func (e *SimpleEngine) Options() []search.Option { return nil }
+
+func (e *SimpleEngine) SetOption(name, value string) error {
+	return fmt.Errorf("engine %s has no option %q", e.name, name)
+}
+
 func (e *SimpleEngine) Go(pos *position.Position, searchOptions search.SearchOptions) error {
+	// With no legal moves there is nothing to choose from, so "0000" is sent as the UCI protocol asks.
+	if reason := pos.Outcome().Reason; reason == position.ReasonCheckmate || reason == position.ReasonStalemate {
+		fmt.Println("bestmove 0000")
+		return nil
+	}
+
 	bestMove, err := e.chooseMove(pos, searchOptions)
 	if err != nil {
 		return err
@@ -0,0 +1,287 @@
+// Package epd reads positions in Extended Position Description (EPD) format, which is used for test suites such as
+// Win At Chess (WAC) and the Strategic Test Suite (STS), as well as for perft suites.
+//
+// An EPD record is the first four fields of a FEN string followed by a list of operations, each an opcode followed by
+// some operands and terminated by a semicolon:
+//
+//	r1b1k2r/ppppnppp/2n2q2/2b5/3NP3/2P1B3/PP3PPP/RN1QKB1R w KQkq - bm Nb5; id "WAC.010";
+//
+// For more information: https://www.chessprogramming.org/Extended_Position_Description
+package epd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ollybritton/StupidChess/position"
+)
+
+// Operation is a single operation in an EPD record, e.g. bm Nb5 Qf3.
+type Operation struct {
+	Opcode   string
+	Operands []string
+}
+
+// EPD is a single EPD record.
+type EPD struct {
+	Position   *position.Position
+	Operations []Operation
+}
+
+// Parse parses a single EPD record. The "hmvc" and "fmvn" operations are used for the position's halfmove clock and
+// full move number if they are present.
+//
+// Some files, such as the well-known perft suites, use a full FEN string followed by operations with semicolons before
+// them rather than after. These are accepted too.
+func Parse(line string) (*EPD, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 {
+		return nil, fmt.Errorf("invalid epd %q, expecting at least 4 fields", line)
+	}
+
+	rest := skipFields(line, 4)
+	halfmoveClock, fullMoves := "0", "1"
+
+	if len(fields) >= 6 && isNumber(fields[4]) && isNumber(fields[5]) {
+		halfmoveClock, fullMoves = fields[4], fields[5]
+		rest = skipFields(rest, 2)
+	}
+
+	operations, err := parseOperations(line, rest)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &EPD{Operations: operations}
+
+	if operands, ok := e.Operation("hmvc"); ok && len(operands) == 1 {
+		halfmoveClock = operands[0]
+	}
+
+	if operands, ok := e.Operation("fmvn"); ok && len(operands) == 1 {
+		fullMoves = operands[0]
+	}
+
+	pos, err := position.NewPositionFromFEN(strings.Join(append(fields[:4:4], halfmoveClock, fullMoves), " "))
+	if err != nil {
+		return nil, fmt.Errorf("invalid epd %q: %w", line, err)
+	}
+
+	e.Position = pos
+
+	return e, nil
+}
+
+// skipFields returns the string with the first n whitespace-separated fields removed.
+func skipFields(str string, n int) string {
+	for i := 0; i < n; i++ {
+		str = strings.TrimLeft(str, " \t")
+
+		end := strings.IndexAny(str, " \t")
+		if end == -1 {
+			return ""
+		}
+
+		str = str[end:]
+	}
+
+	return str
+}
+
+func isNumber(str string) bool {
+	_, err := strconv.ParseUint(str, 10, 0)
+	return err == nil
+}
+
+// parseOperations splits the operations part of an EPD record into opcodes and operands. Operands can be quoted
+// strings, which may contain spaces and semicolons.
+func parseOperations(line, str string) ([]Operation, error) {
+	operations := []Operation{}
+	fields := []string{}
+
+	for i := 0; i < len(str); {
+		switch c := str[i]; {
+		case c == ' ' || c == '\t':
+			i++
+
+		case c == ';':
+			if len(fields) != 0 {
+				operations = append(operations, Operation{Opcode: fields[0], Operands: fields[1:]})
+				fields = []string{}
+			}
+
+			i++
+
+		case c == '"':
+			end := strings.IndexByte(str[i+1:], '"')
+			if end == -1 {
+				return nil, fmt.Errorf("invalid epd %q, unterminated string", line)
+			}
+
+			fields = append(fields, str[i+1:i+1+end])
+			i += end + 2
+
+		default:
+			end := strings.IndexAny(str[i:], " \t;")
+			if end == -1 {
+				end = len(str) - i
+			}
+
+			fields = append(fields, str[i:i+end])
+			i += end
+		}
+	}
+
+	// The last operation should be terminated by a semicolon, but it's often left out.
+	if len(fields) != 0 {
+		operations = append(operations, Operation{Opcode: fields[0], Operands: fields[1:]})
+	}
+
+	return operations, nil
+}
+
+// ReadAll reads every EPD record from r, one per line. Blank lines and lines starting with "#" are skipped.
+func ReadAll(r io.Reader) ([]*EPD, error) {
+	records := []*EPD{}
+	scanner := bufio.NewScanner(r)
+
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		record, err := Parse(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		records = append(records, record)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// Operation returns the operands for the given opcode, and whether the record has that opcode at all.
+func (e *EPD) Operation(opcode string) ([]string, bool) {
+	for _, operation := range e.Operations {
+		if operation.Opcode == opcode {
+			return operation.Operands, true
+		}
+	}
+
+	return nil, false
+}
+
+// ID returns the "id" operation, which names the position, or the empty string if there isn't one.
+func (e *EPD) ID() string {
+	operands, _ := e.Operation("id")
+	return strings.Join(operands, " ")
+}
+
+// Comment returns the "c0" to "c9" comment operations, or the empty string if there isn't one.
+func (e *EPD) Comment(n int) string {
+	operands, _ := e.Operation(fmt.Sprintf("c%d", n))
+	return strings.Join(operands, " ")
+}
+
+// BestMoves returns the moves in the "bm" operation, which are the moves that solve the position.
+func (e *EPD) BestMoves() ([]position.Move, error) {
+	return e.moves("bm")
+}
+
+// AvoidMoves returns the moves in the "am" operation, which are the moves that should not be played.
+func (e *EPD) AvoidMoves() ([]position.Move, error) {
+	return e.moves("am")
+}
+
+func (e *EPD) moves(opcode string) ([]position.Move, error) {
+	operands, _ := e.Operation(opcode)
+	moves := make([]position.Move, 0, len(operands))
+
+	for _, operand := range operands {
+		move, err := e.Position.ParseSAN(operand)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s operation in epd %q: %w", opcode, e.ID(), err)
+		}
+
+		moves = append(moves, move)
+	}
+
+	return moves, nil
+}
+
+// AnalysisDepth returns the depth from the "acd" operation, and whether the record has one.
+func (e *EPD) AnalysisDepth() (uint, bool, error) {
+	operands, ok := e.Operation("acd")
+	if !ok {
+		return 0, false, nil
+	}
+
+	if len(operands) != 1 {
+		return 0, false, fmt.Errorf("invalid acd operation in epd %q, expecting a single depth", e.ID())
+	}
+
+	depth, err := strconv.ParseUint(operands[0], 10, 0)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid acd operation in epd %q: %w", e.ID(), err)
+	}
+
+	return uint(depth), true, nil
+}
+
+// PerftCounts returns the expected perft results given by the "D1" to "D6" operations, indexed by depth. Depths
+// without an operation are left out.
+func (e *EPD) PerftCounts() (map[uint]uint64, error) {
+	counts := map[uint]uint64{}
+
+	for depth := uint(1); depth <= 6; depth++ {
+		opcode := fmt.Sprintf("D%d", depth)
+
+		operands, ok := e.Operation(opcode)
+		if !ok {
+			continue
+		}
+
+		if len(operands) != 1 {
+			return nil, fmt.Errorf("invalid %s operation in epd %q, expecting a single count", opcode, e.ID())
+		}
+
+		count, err := strconv.ParseUint(operands[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s operation in epd %q: %w", opcode, e.ID(), err)
+		}
+
+		counts[depth] = count
+	}
+
+	return counts, nil
+}
+
+// String returns the record in EPD format, with the operations in their original order.
+func (e *EPD) String() string {
+	fields := strings.Fields(e.Position.StringFEN())[:4]
+
+	for _, operation := range e.Operations {
+		field := operation.Opcode
+
+		for _, operand := range operation.Operands {
+			if strings.ContainsAny(operand, " ;") || operand == "" {
+				operand = `"` + operand + `"`
+			}
+
+			field += " " + operand
+		}
+
+		fields = append(fields, field+";")
+	}
+
+	return strings.Join(fields, " ")
+}
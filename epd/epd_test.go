@@ -0,0 +1,102 @@
+package epd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ollybritton/StupidChess/position"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParse tests that the position and operations in an EPD record are parsed correctly.
+func TestParse(t *testing.T) {
+	record, err := Parse(`r1b1k2r/ppppnppp/2n2q2/2b5/3NP3/2P1B3/PP3PPP/RN1QKB1R w KQkq - bm Nb5; id "WAC.010"; c0 "a comment; with a semicolon"; acd 12; hmvc 3; fmvn 9;`)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "r1b1k2r/ppppnppp/2n2q2/2b5/3NP3/2P1B3/PP3PPP/RN1QKB1R w KQkq - 3 9", record.Position.StringFEN())
+	assert.Equal(t, "WAC.010", record.ID())
+	assert.Equal(t, "a comment; with a semicolon", record.Comment(0))
+	assert.Equal(t, "", record.Comment(1))
+
+	bestMoves, err := record.BestMoves()
+	assert.NoError(t, err)
+	assert.Len(t, bestMoves, 1)
+	assert.Equal(t, "d4b5", bestMoves[0].String())
+
+	depth, ok, err := record.AnalysisDepth()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, uint(12), depth)
+
+	assert.Equal(t, `r1b1k2r/ppppnppp/2n2q2/2b5/3NP3/2P1B3/PP3PPP/RN1QKB1R w KQkq - bm Nb5; id WAC.010; c0 "a comment; with a semicolon"; acd 12; hmvc 3; fmvn 9;`, record.String())
+}
+
+// TestParsePerftSuite tests that records in the format used by common perft suites, with a full FEN string and
+// semicolons before each operation, are understood.
+func TestParsePerftSuite(t *testing.T) {
+	record, err := Parse("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 ;D1 20 ;D2 400 ;D3 8902")
+	assert.NoError(t, err)
+
+	assert.Equal(t, position.StartingPosition, record.Position.StringFEN())
+
+	counts, err := record.PerftCounts()
+	assert.NoError(t, err)
+	assert.Equal(t, map[uint]uint64{1: 20, 2: 400, 3: 8902}, counts)
+}
+
+// TestParseInvalid tests that invalid EPD records give an error.
+func TestParseInvalid(t *testing.T) {
+	for _, line := range []string{
+		"",
+		"8/8/8/8 w",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - id \"unterminated;",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR x KQkq - bm e4;",
+	} {
+		_, err := Parse(line)
+		assert.Error(t, err, line)
+	}
+}
+
+// TestScore tests that moves are scored using the best moves, moves to avoid and STS-style points tables.
+func TestScore(t *testing.T) {
+	records, err := ReadAll(strings.NewReader(`
+# A comment
+1k1r4/pp1b1R2/3q2pp/4p3/2B5/4Q3/PPP2B2/2K5 b - - bm Qd1+; id "bm";
+1k1r4/pp1b1R2/3q2pp/4p3/2B5/4Q3/PPP2B2/2K5 b - - am Qd1+; id "am";
+1k1r4/pp1b1R2/3q2pp/4p3/2B5/4Q3/PPP2B2/2K5 b - - bm Qd1+; c0 "Qd1+=10, Qb4=3"; id "sts";
+`))
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+
+	pos := records[0].Position
+	check, err := pos.ParseSAN("Qd1+")
+	assert.NoError(t, err)
+	other, err := pos.ParseSAN("Qb4")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		record    *EPD
+		move      position.Move
+		solved    bool
+		points    int
+		maxPoints int
+	}{
+		{records[0], check, true, 1, 1},
+		{records[0], other, false, 0, 1},
+		{records[1], check, false, 0, 1},
+		{records[1], other, true, 1, 1},
+		{records[2], check, true, 10, 10},
+		{records[2], other, false, 3, 10},
+	}
+
+	for _, test := range tests {
+		solved, err := test.record.Solved(test.move)
+		assert.NoError(t, err)
+		assert.Equal(t, test.solved, solved, test.record.ID())
+
+		points, maxPoints, err := test.record.Score(test.move)
+		assert.NoError(t, err)
+		assert.Equal(t, test.points, points, test.record.ID())
+		assert.Equal(t, test.maxPoints, maxPoints, test.record.ID())
+	}
+}
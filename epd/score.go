@@ -0,0 +1,105 @@
+package epd
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ollybritton/StupidChess/position"
+)
+
+// Solved returns true if the move solves the position, i.e. it is one of the best moves given by the "bm" operation
+// and isn't one of the moves to avoid given by the "am" operation.
+func (e *EPD) Solved(move position.Move) (bool, error) {
+	bestMoves, err := e.BestMoves()
+	if err != nil {
+		return false, err
+	}
+
+	avoidMoves, err := e.AvoidMoves()
+	if err != nil {
+		return false, err
+	}
+
+	if len(bestMoves) != 0 && !containsMove(bestMoves, move) {
+		return false, nil
+	}
+
+	return !containsMove(avoidMoves, move), nil
+}
+
+// Score returns the points scored by playing the move in the position, and the most points that could have been scored.
+//
+// Suites such as STS give partial credit for moves other than the best move, using a "c0" comment listing moves and
+// their points:
+//
+//	c0 "Qd2=10, Qe2=4, Qf3=2";
+//
+// If the record has a list like this, it's used for the score. Otherwise a move scores 1 point if it solves the
+// position and 0 otherwise.
+func (e *EPD) Score(move position.Move) (points int, max int, err error) {
+	if table, ok := e.pointsTable(); ok {
+		for candidate, value := range table {
+			if candidate.Equal(move) {
+				points = value
+			}
+
+			if value > max {
+				max = value
+			}
+		}
+
+		return points, max, nil
+	}
+
+	solved, err := e.Solved(move)
+	if err != nil {
+		return 0, 1, err
+	}
+
+	if solved {
+		return 1, 1, nil
+	}
+
+	return 0, 1, nil
+}
+
+// pointsTable parses the list of moves and points in the "c0" comment, if there is one.
+func (e *EPD) pointsTable() (map[position.Move]int, bool) {
+	comment := e.Comment(0)
+	if !strings.Contains(comment, "=") {
+		return nil, false
+	}
+
+	table := map[position.Move]int{}
+
+	for _, entry := range strings.Split(comment, ",") {
+		san, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			return nil, false
+		}
+
+		points, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, false
+		}
+
+		move, err := e.Position.ParseSAN(san)
+		if err != nil {
+			return nil, false
+		}
+
+		table[move] = points
+	}
+
+	return table, true
+}
+
+func containsMove(moves []position.Move, move position.Move) bool {
+	for _, m := range moves {
+		if m.Equal(move) {
+			return true
+		}
+	}
+
+	return false
+}
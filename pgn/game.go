@@ -0,0 +1,166 @@
+// Package pgn reads and writes chess games in Portable Game Notation (PGN).
+//
+// For more information: https://www.chessprogramming.org/Portable_Game_Notation
+package pgn
+
+import (
+	"fmt"
+
+	"github.com/ollybritton/StupidChess/position"
+)
+
+// Possible values for the result of a game, used both in the "Result" tag and at the end of the movetext.
+const (
+	ResultWhiteWins = "1-0"
+	ResultBlackWins = "0-1"
+	ResultDraw      = "1/2-1/2"
+	ResultUnknown   = "*"
+)
+
+// sevenTagRoster is the list of tags that every game in export format must have, in the order they must appear.
+var sevenTagRoster = []string{"Event", "Site", "Date", "Round", "White", "Black", "Result"}
+
+// Tag is a single tag pair from the header of a game, e.g. [White "Kasparov, Garry"].
+type Tag struct {
+	Name  string
+	Value string
+}
+
+// Move is a single move in the movetext of a game, along with any annotations attached to it.
+type Move struct {
+	Move          position.Move
+	NAGs          []int     // NAGs are the Numeric Annotation Glyphs following the move, e.g. 1 for "!".
+	CommentBefore string    // CommentBefore is a comment appearing before the move, only used for the first move of a variation.
+	CommentAfter  string    // CommentAfter is a comment appearing after the move.
+	Variations    [][]*Move // Variations are alternatives to this move, each starting from the position before it was played.
+}
+
+// Game is a single game from a PGN file.
+type Game struct {
+	Tags    []Tag
+	Comment string  // Comment is a comment appearing before the first move of the game.
+	Moves   []*Move // Moves is the main line of the game.
+	Result  string  // Result is the game termination marker, one of the Result constants.
+}
+
+// NewGame returns a new game with the Seven Tag Roster filled in with unknown values. If the starting position isn't
+// the standard one, it is recorded in the "SetUp" and "FEN" tags.
+func NewGame(start *position.Position) *Game {
+	g := &Game{Result: ResultUnknown}
+
+	g.SetTag("Event", "?")
+	g.SetTag("Site", "?")
+	g.SetTag("Date", "????.??.??")
+	g.SetTag("Round", "?")
+	g.SetTag("White", "?")
+	g.SetTag("Black", "?")
+	g.SetTag("Result", ResultUnknown)
+
+	if fen := start.StringFEN(); fen != position.StartingPosition {
+		g.SetTag("SetUp", "1")
+		g.SetTag("FEN", fen)
+	}
+
+	return g
+}
+
+// Tag returns the value of the tag with the given name, or the empty string if the game doesn't have it.
+func (g *Game) Tag(name string) string {
+	for _, tag := range g.Tags {
+		if tag.Name == name {
+			return tag.Value
+		}
+	}
+
+	return ""
+}
+
+// SetTag sets the value of a tag, adding it if the game doesn't have it already.
+func (g *Game) SetTag(name, value string) {
+	for i, tag := range g.Tags {
+		if tag.Name == name {
+			g.Tags[i].Value = value
+			return
+		}
+	}
+
+	g.Tags = append(g.Tags, Tag{Name: name, Value: value})
+}
+
+// SetResult sets the result of the game, both in the movetext and in the "Result" tag.
+func (g *Game) SetResult(result string) {
+	g.Result = result
+	g.SetTag("Result", result)
+}
+
+// StartingPosition returns the position the game starts from, which is given by the "FEN" tag if there is one and the
+// standard starting position otherwise.
+func (g *Game) StartingPosition() (*position.Position, error) {
+	fen := g.Tag("FEN")
+	if fen == "" {
+		fen = position.StartingPosition
+	}
+
+	pos, err := position.NewPositionFromFEN(fen)
+	if err != nil {
+		return nil, fmt.Errorf("invalid FEN tag: %w", err)
+	}
+
+	return pos, nil
+}
+
+// Position returns the position at the end of the main line. Since every move is made on the position, it keeps the
+// history of the game, so repetitions can still be detected.
+func (g *Game) Position() (*position.Position, error) {
+	pos, err := g.StartingPosition()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, move := range g.Moves {
+		if !pos.MakeMove(move.Move) {
+			return nil, fmt.Errorf("move %d (%s) is illegal in position %s", i+1, move.Move, pos.StringFEN())
+		}
+	}
+
+	return pos, nil
+}
+
+// Positions returns the position before every move in the main line, followed by the final position.
+func (g *Game) Positions() ([]*position.Position, error) {
+	pos, err := g.StartingPosition()
+	if err != nil {
+		return nil, err
+	}
+
+	positions := []*position.Position{pos.Copy()}
+
+	for i, move := range g.Moves {
+		if !pos.MakeMove(move.Move) {
+			return nil, fmt.Errorf("move %d (%s) is illegal in position %s", i+1, move.Move, pos.StringFEN())
+		}
+
+		positions = append(positions, pos.Copy())
+	}
+
+	return positions, nil
+}
+
+// AddMove appends a move to the end of the main line. Only the from and to squares and the promotion of the move are
+// used, so moves from position.ParseMove can be added. It returns an error if the move isn't legal at the end of the
+// main line.
+func (g *Game) AddMove(move position.Move) error {
+	pos, err := g.Position()
+	if err != nil {
+		return err
+	}
+
+	for _, legal := range pos.MovesLegal().AsSlice() {
+		if legal.From() == move.From() && legal.To() == move.To() && legal.Promotion() == move.Promotion() {
+			g.Moves = append(g.Moves, &Move{Move: legal})
+			return nil
+		}
+	}
+
+	return fmt.Errorf("move %s is illegal in position %s", move, pos.StringFEN())
+}
@@ -0,0 +1,253 @@
+package pgn
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// tokenType is the type of a token in a PGN file.
+type tokenType uint8
+
+const (
+	tokenEOF            tokenType = iota
+	tokenTagOpen                  // [
+	tokenTagClose                 // ]
+	tokenString                   // "a string", with escapes removed
+	tokenSymbol                   // e4, Nbd7, O-O, 12, Event, 1-0, etc.
+	tokenPeriod                   // .
+	tokenComment                  // {a comment} or ; a comment until the end of the line
+	tokenNAG                      // $12
+	tokenAnnotation               // !, ?, !!, ??, !? or ?!
+	tokenVariationOpen            // (
+	tokenVariationClose           // )
+	tokenAsterisk                 // *
+)
+
+// token is a single token in a PGN file.
+type token struct {
+	typ   tokenType
+	value string
+	line  int
+}
+
+// syntaxError is an error caused by malformed input rather than by failing to read it.
+type syntaxError struct {
+	line int
+	msg  string
+}
+
+func (e *syntaxError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.line, e.msg)
+}
+
+// lexer splits a PGN file into tokens. It reads from the underlying reader as needed, so large files can be parsed
+// without loading them into memory all at once.
+type lexer struct {
+	in     *bufio.Reader
+	line   int
+	column int
+	peeked *token
+}
+
+func newLexer(r io.Reader) *lexer {
+	return &lexer{in: bufio.NewReader(r), line: 1}
+}
+
+// peek returns the next token without consuming it.
+func (l *lexer) peek() (token, error) {
+	if l.peeked == nil {
+		tok, err := l.scan()
+		if err != nil {
+			return token{}, err
+		}
+
+		l.peeked = &tok
+	}
+
+	return *l.peeked, nil
+}
+
+// next consumes and returns the next token.
+func (l *lexer) next() (token, error) {
+	tok, err := l.peek()
+	l.peeked = nil
+
+	return tok, err
+}
+
+func (l *lexer) read() (rune, error) {
+	r, _, err := l.in.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+
+	if r == '\n' {
+		l.line++
+		l.column = 0
+	} else {
+		l.column++
+	}
+
+	return r, nil
+}
+
+func (l *lexer) unread() {
+	l.in.UnreadRune()
+	l.column--
+}
+
+// scan reads the next token from the input.
+func (l *lexer) scan() (token, error) {
+	for {
+		r, err := l.read()
+		if err == io.EOF {
+			return token{typ: tokenEOF, line: l.line}, nil
+		} else if err != nil {
+			return token{}, err
+		}
+
+		line := l.line
+
+		switch {
+		case r == '%' && l.column == 1:
+			// A percent sign at the start of a line means the rest of the line should be ignored.
+			if _, err := l.readUntil('\n'); err != nil {
+				return token{typ: tokenEOF, line: line}, nil
+			}
+
+		case isSpace(r):
+			continue
+
+		case r == '[':
+			return token{typ: tokenTagOpen, value: "[", line: line}, nil
+		case r == ']':
+			return token{typ: tokenTagClose, value: "]", line: line}, nil
+		case r == '(':
+			return token{typ: tokenVariationOpen, value: "(", line: line}, nil
+		case r == ')':
+			return token{typ: tokenVariationClose, value: ")", line: line}, nil
+		case r == '.':
+			return token{typ: tokenPeriod, value: ".", line: line}, nil
+		case r == '*':
+			return token{typ: tokenAsterisk, value: "*", line: line}, nil
+
+		case r == '"':
+			return l.scanString(line)
+
+		case r == '{':
+			comment, err := l.readUntil('}')
+			if err != nil {
+				return token{}, l.errorf(line, "unterminated comment")
+			}
+
+			return token{typ: tokenComment, value: strings.Join(strings.Fields(comment), " "), line: line}, nil
+
+		case r == ';':
+			comment, _ := l.readUntil('\n')
+			return token{typ: tokenComment, value: strings.TrimSpace(comment), line: line}, nil
+
+		case r == '$':
+			digits := l.readWhile(func(r rune) bool { return r >= '0' && r <= '9' })
+			if digits == "" {
+				return token{}, l.errorf(line, "expecting a number after '$'")
+			}
+
+			return token{typ: tokenNAG, value: digits, line: line}, nil
+
+		case r == '!' || r == '?':
+			rest := l.readWhile(func(r rune) bool { return r == '!' || r == '?' })
+			return token{typ: tokenAnnotation, value: string(r) + rest, line: line}, nil
+
+		case isSymbolStart(r):
+			rest := l.readWhile(isSymbolContinuation)
+			return token{typ: tokenSymbol, value: string(r) + rest, line: line}, nil
+
+		default:
+			return token{}, l.errorf(line, "unexpected character %q", r)
+		}
+	}
+}
+
+// errorf returns a syntax error for the given line.
+func (l *lexer) errorf(line int, format string, args ...interface{}) error {
+	return &syntaxError{line: line, msg: fmt.Sprintf(format, args...)}
+}
+
+// scanString reads a string token, having already read the opening quote.
+func (l *lexer) scanString(line int) (token, error) {
+	var out strings.Builder
+
+	for {
+		r, err := l.read()
+		if err != nil {
+			return token{}, l.errorf(line, "unterminated string")
+		}
+
+		switch r {
+		case '"':
+			return token{typ: tokenString, value: out.String(), line: line}, nil
+
+		case '\\':
+			escaped, err := l.read()
+			if err != nil {
+				return token{}, l.errorf(line, "unterminated string")
+			}
+
+			out.WriteRune(escaped)
+
+		default:
+			out.WriteRune(r)
+		}
+	}
+}
+
+// readUntil reads until the given delimiter, returning everything before it.
+func (l *lexer) readUntil(delim rune) (string, error) {
+	var out strings.Builder
+
+	for {
+		r, err := l.read()
+		if err != nil {
+			return out.String(), err
+		}
+
+		if r == delim {
+			return out.String(), nil
+		}
+
+		out.WriteRune(r)
+	}
+}
+
+// readWhile reads runes for as long as they match the given function.
+func (l *lexer) readWhile(match func(rune) bool) string {
+	var out strings.Builder
+
+	for {
+		r, err := l.read()
+		if err != nil {
+			return out.String()
+		}
+
+		if !match(r) {
+			l.unread()
+			return out.String()
+		}
+
+		out.WriteRune(r)
+	}
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\v' || r == '\f' || r == '\uFEFF'
+}
+
+func isSymbolStart(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
+
+func isSymbolContinuation(r rune) bool {
+	return isSymbolStart(r) || strings.ContainsRune("_+#=:-/", r)
+}
@@ -0,0 +1,187 @@
+package pgn
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ollybritton/StupidChess/position"
+	"github.com/stretchr/testify/assert"
+)
+
+const testGames = `[Event "F/S Return Match"]
+[Site "Belgrade, Serbia JUG"]
+[Date "1992.11.04"]
+[Round "29"]
+[White "Fischer, Robert J."]
+[Black "Spassky, Boris V."]
+[Result "1/2-1/2"]
+
+1. e4 e5 2. Nf3 Nc6 3. Bb5 {This opening is called the Ruy Lopez.} 3... a6
+4. Ba4 Nf6 5. O-O Be7 6. Re1 b5 7. Bb3 d6 8. c3 O-O 9. h3 Nb8 10. d4 Nbd7
+11. c4 c6 12. cxb5 axb5 13. Nc3 Bb7 14. Bg5 b4 15. Nb1 h6 16. Bh4 c5 17. dxe5
+Nxe4 18. Bxe7 Qxe7 19. exd6 Qf6 20. Nbd2 Nxd6 21. Nc4 Nxc4 22. Bxc4 Nb6
+23. Ne5 Rae8 24. Bxf7+ Rxf7 25. Nxf7 Rxe1+ 26. Qxe1 Kxf7 27. Qe3 Qg5 28. Qxg5
+hxg5 29. b3 Ke6 30. a3 Kd6 31. axb4 cxb4 32. Ra5 Nd5 33. f3 Bc8 34. Kf2 Bf5
+35. Ra7 g6 36. Ra6+ Kc5 37. Ke1 Nf4 38. g3 Nxh3 39. Kd2 Kb5 40. Rd6 Kc5 41. Ra6
+Nf2 42. g4 Bd3 43. Re6 1/2-1/2
+
+% This line is ignored.
+[Event "Variations"]
+[SetUp "1"]
+[FEN "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1"]
+
+{Starting comment} 1. e4! ({Alternatively} 1. e3 Kd7 (1... Ke7 2. Kd2 $10) 2. Kd2) 1... Kd7 $14 ; A rest-of-line comment
+2. Kd2 *
+
+1. d4 d5 2. c4 dxc4 0-1
+`
+
+// TestReadAll tests that several games can be read from a single file, including their tags, comments, annotations,
+// variations and results.
+func TestReadAll(t *testing.T) {
+	games, err := ReadAll(strings.NewReader(testGames))
+	assert.NoError(t, err)
+	assert.Len(t, games, 3)
+
+	fischer := games[0]
+	assert.Equal(t, "Fischer, Robert J.", fischer.Tag("White"))
+	assert.Equal(t, ResultDraw, fischer.Result)
+	assert.Len(t, fischer.Moves, 85)
+	assert.Equal(t, "This opening is called the Ruy Lopez.", fischer.Moves[4].CommentAfter)
+
+	pos, err := fischer.Position()
+	assert.NoError(t, err)
+	assert.Equal(t, "8/8/4R1p1/2k3p1/1p4P1/1P1b1P2/3K1n2/8 b - - 2 43", pos.StringFEN())
+
+	variations := games[1]
+	assert.Equal(t, "Variations", variations.Tag("Event"))
+	assert.Equal(t, "Starting comment", variations.Comment)
+	assert.Equal(t, ResultUnknown, variations.Result)
+	assert.Len(t, variations.Moves, 3)
+
+	e4 := variations.Moves[0]
+	assert.Equal(t, []int{1}, e4.NAGs)
+	assert.Len(t, e4.Variations, 1)
+
+	e3 := e4.Variations[0]
+	assert.Len(t, e3, 3)
+	assert.Equal(t, "e2e3", e3[0].Move.String())
+	assert.Equal(t, "Alternatively", e3[0].CommentBefore)
+	assert.Len(t, e3[1].Variations, 1)
+	assert.Equal(t, "e8e7", e3[1].Variations[0][0].Move.String())
+	assert.Equal(t, []int{10}, e3[1].Variations[0][1].NAGs)
+
+	assert.Equal(t, []int{14}, variations.Moves[1].NAGs)
+	assert.Equal(t, "A rest-of-line comment", variations.Moves[1].CommentAfter)
+
+	assert.Equal(t, "", games[2].Tag("Event"))
+	assert.Equal(t, ResultBlackWins, games[2].Result)
+	assert.Len(t, games[2].Moves, 4)
+}
+
+// TestReaderSkipsInvalidGames tests that an invalid game is reported as an error without stopping later games from
+// being read.
+func TestReaderSkipsInvalidGames(t *testing.T) {
+	reader := NewReader(strings.NewReader(`1. e4 e5 2. Ke3 Nc6 1-0
+1. e4 (1. d4 d5) e5 *
+
+1. d4 ) 1-0
+[Event "Last"]
+
+1. Nf3 *
+`))
+
+	_, err := reader.Next()
+	assert.Error(t, err)
+
+	game, err := reader.Next()
+	assert.NoError(t, err)
+	assert.Len(t, game.Moves, 2)
+	assert.Len(t, game.Moves[0].Variations, 1)
+
+	_, err = reader.Next()
+	assert.Error(t, err)
+
+	game, err = reader.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, "Last", game.Tag("Event"))
+
+	_, err = reader.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+// TestWriteRoundTrip tests that writing a game out and reading it back in gives the same game.
+func TestWriteRoundTrip(t *testing.T) {
+	games, err := ReadAll(strings.NewReader(testGames))
+	assert.NoError(t, err)
+
+	var out strings.Builder
+	writer := NewWriter(&out)
+
+	for _, game := range games {
+		assert.NoError(t, writer.Write(game))
+	}
+
+	for _, line := range strings.Split(out.String(), "\n") {
+		assert.LessOrEqual(t, len(line), maxLineLength)
+	}
+
+	reread, err := ReadAll(strings.NewReader(out.String()))
+	assert.NoError(t, err)
+	assert.Len(t, reread, len(games))
+
+	for i := range games {
+		assert.Equal(t, games[i].Moves, reread[i].Moves)
+		assert.Equal(t, games[i].Comment, reread[i].Comment)
+		assert.Equal(t, games[i].Result, reread[i].Result)
+		assert.Equal(t, games[i].Result, reread[i].Tag("Result"))
+	}
+
+	assert.Equal(t, `[Event "Variations"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "?"]
+[Black "?"]
+[Result "*"]
+[FEN "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1"]
+[SetUp "1"]
+
+{Starting comment} 1. e4 $1 ({Alternatively} 1. e3 Kd7 (1... Ke7 2. Kd2 $10) 2.
+Kd2) 1... Kd7 $14 {A rest-of-line comment} 2. Kd2 *
+
+`, games[1].String())
+}
+
+// TestAddMove tests that games can be built up move by move.
+func TestAddMove(t *testing.T) {
+	pos, err := position.NewPositionFromFEN(position.StartingPosition)
+	assert.NoError(t, err)
+
+	game := NewGame(pos)
+
+	for _, str := range []string{"f2f3", "e7e5", "g2g4", "d8h4"} {
+		move, err := position.ParseMove(str)
+		assert.NoError(t, err)
+		assert.NoError(t, game.AddMove(move))
+	}
+
+	move, err := position.ParseMove("e1f2")
+	assert.NoError(t, err)
+	assert.Error(t, game.AddMove(move))
+
+	game.SetResult(ResultBlackWins)
+
+	assert.Equal(t, `[Event "?"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "?"]
+[Black "?"]
+[Result "0-1"]
+
+1. f3 e5 2. g4 Qh4# 0-1
+
+`, game.String())
+}
@@ -0,0 +1,307 @@
+package pgn
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/ollybritton/StupidChess/position"
+)
+
+// annotationNAGs maps the traditional suffix annotations to their equivalent Numeric Annotation Glyphs.
+var annotationNAGs = map[string]int{
+	"!":  1,
+	"?":  2,
+	"!!": 3,
+	"??": 4,
+	"!?": 5,
+	"?!": 6,
+}
+
+// Reader reads games from a PGN file one at a time, so that large databases don't have to be loaded into memory all at
+// once.
+type Reader struct {
+	lex   *lexer
+	games int
+}
+
+// NewReader returns a new Reader reading games from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{lex: newLexer(r)}
+}
+
+// ReadAll reads every game from r.
+func ReadAll(r io.Reader) ([]*Game, error) {
+	reader := NewReader(r)
+	games := []*Game{}
+
+	for {
+		game, err := reader.Next()
+		if err == io.EOF {
+			return games, nil
+		} else if err != nil {
+			return games, err
+		}
+
+		games = append(games, game)
+	}
+}
+
+// Next reads the next game, returning io.EOF once there are no more games. If a game can't be parsed, the rest of it is
+// skipped so that Next can be called again to carry on with the following game.
+func (r *Reader) Next() (*Game, error) {
+	tok, err := r.lex.peek()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok.typ == tokenEOF {
+		return nil, io.EOF
+	}
+
+	r.games++
+
+	game, err := r.readGame()
+	if err != nil {
+		r.skipGame()
+		return nil, fmt.Errorf("error reading game %d: %w", r.games, err)
+	}
+
+	return game, nil
+}
+
+// readGame reads the tag pairs and movetext of a single game.
+func (r *Reader) readGame() (*Game, error) {
+	game := &Game{}
+
+	for {
+		tok, err := r.lex.peek()
+		if err != nil {
+			return nil, err
+		}
+
+		if tok.typ != tokenTagOpen {
+			break
+		}
+
+		tag, err := r.readTag()
+		if err != nil {
+			return nil, err
+		}
+
+		game.Tags = append(game.Tags, tag)
+	}
+
+	pos, err := game.StartingPosition()
+	if err != nil {
+		return nil, err
+	}
+
+	moves, comment, result, err := r.readLine(pos, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	game.Moves = moves
+	game.Comment = comment
+	game.Result = result
+
+	return game, nil
+}
+
+// readTag reads a single tag pair, e.g. [Event "F/S Return Match"].
+func (r *Reader) readTag() (Tag, error) {
+	open, err := r.lex.next()
+	if err != nil {
+		return Tag{}, err
+	}
+
+	name, err := r.lex.next()
+	if err != nil {
+		return Tag{}, err
+	}
+
+	if name.typ != tokenSymbol {
+		return Tag{}, fmt.Errorf("line %d: expecting tag name, got %q", name.line, name.value)
+	}
+
+	value, err := r.lex.next()
+	if err != nil {
+		return Tag{}, err
+	}
+
+	if value.typ != tokenString {
+		return Tag{}, fmt.Errorf("line %d: expecting quoted value for tag %s, got %q", value.line, name.value, value.value)
+	}
+
+	close, err := r.lex.next()
+	if err != nil {
+		return Tag{}, err
+	}
+
+	if close.typ != tokenTagClose {
+		return Tag{}, fmt.Errorf("line %d: expecting ']' to close tag opened on line %d, got %q", close.line, open.line, close.value)
+	}
+
+	return Tag{Name: name.value, Value: value.value}, nil
+}
+
+// readLine reads a sequence of moves starting from the given position, which is either the main line of a game or a
+// variation at the given depth. It returns the moves, any comment appearing before the first move, and the game
+// termination marker if it reached the end of the game.
+func (r *Reader) readLine(pos *position.Position, depth int) (moves []*Move, comment string, result string, err error) {
+	for {
+		tok, err := r.lex.peek()
+		if err != nil {
+			return nil, "", "", err
+		}
+
+		// A tag at the start of a line means that the game ended without a termination marker, so it's left for the
+		// next game.
+		if tok.typ == tokenTagOpen || tok.typ == tokenEOF {
+			if depth > 0 {
+				return nil, "", "", fmt.Errorf("line %d: unterminated variation", tok.line)
+			}
+
+			return moves, comment, ResultUnknown, nil
+		}
+
+		r.lex.next()
+
+		var last *Move
+		if len(moves) > 0 {
+			last = moves[len(moves)-1]
+		}
+
+		switch tok.typ {
+		case tokenPeriod:
+			continue
+
+		case tokenAsterisk:
+			if depth > 0 {
+				return nil, "", "", fmt.Errorf("line %d: unexpected game termination inside variation", tok.line)
+			}
+
+			return moves, comment, ResultUnknown, nil
+
+		case tokenSymbol:
+			if isResult(tok.value) {
+				if depth > 0 {
+					return nil, "", "", fmt.Errorf("line %d: unexpected game termination inside variation", tok.line)
+				}
+
+				return moves, comment, tok.value, nil
+			}
+
+			if isMoveNumber(tok.value) {
+				continue
+			}
+
+			move, err := pos.ParseSAN(tok.value)
+			if err != nil {
+				return nil, "", "", fmt.Errorf("line %d: %w", tok.line, err)
+			}
+
+			pos.MakeMove(move)
+			moves = append(moves, &Move{Move: move})
+
+		case tokenComment:
+			if last == nil {
+				comment = joinComments(comment, tok.value)
+			} else {
+				last.CommentAfter = joinComments(last.CommentAfter, tok.value)
+			}
+
+		case tokenNAG, tokenAnnotation:
+			if last == nil {
+				return nil, "", "", fmt.Errorf("line %d: annotation %q doesn't follow a move", tok.line, tok.value)
+			}
+
+			nag, ok := annotationNAGs[tok.value]
+			if tok.typ == tokenNAG {
+				nag, err = strconv.Atoi(tok.value)
+				ok = err == nil && nag <= 255
+			}
+
+			if !ok {
+				return nil, "", "", fmt.Errorf("line %d: invalid annotation %q", tok.line, tok.value)
+			}
+
+			last.NAGs = append(last.NAGs, nag)
+
+		case tokenVariationOpen:
+			if last == nil {
+				return nil, "", "", fmt.Errorf("line %d: variation doesn't follow a move", tok.line)
+			}
+
+			// A variation is an alternative to the last move, so it starts from the position before it.
+			branch := pos.Copy()
+			branch.UndoMove(last.Move)
+
+			variation, variationComment, _, err := r.readLine(branch, depth+1)
+			if err != nil {
+				return nil, "", "", err
+			}
+
+			if len(variation) > 0 {
+				variation[0].CommentBefore = variationComment
+				last.Variations = append(last.Variations, variation)
+			}
+
+		case tokenVariationClose:
+			if depth == 0 {
+				return nil, "", "", fmt.Errorf("line %d: unexpected ')' outside of a variation", tok.line)
+			}
+
+			return moves, comment, "", nil
+
+		default:
+			return nil, "", "", fmt.Errorf("line %d: unexpected %q in movetext", tok.line, tok.value)
+		}
+	}
+}
+
+// skipGame skips tokens until the end of the current game, so that reading can carry on after an error.
+func (r *Reader) skipGame() {
+	for {
+		tok, err := r.lex.next()
+		if _, ok := err.(*syntaxError); ok {
+			continue
+		} else if err != nil {
+			return
+		}
+
+		if tok.typ == tokenEOF || tok.typ == tokenAsterisk || (tok.typ == tokenSymbol && isResult(tok.value)) {
+			return
+		}
+	}
+}
+
+// isResult returns true if the symbol is a game termination marker.
+func isResult(symbol string) bool {
+	return symbol == ResultWhiteWins || symbol == ResultBlackWins || symbol == ResultDraw
+}
+
+// isMoveNumber returns true if the symbol is a move number indication, e.g. the "12" in "12. Nf3" or "12... Nf6".
+func isMoveNumber(symbol string) bool {
+	for _, r := range symbol {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// joinComments joins two comments with a space between them, ignoring either if it is empty.
+func joinComments(a, b string) string {
+	if a == "" {
+		return b
+	}
+
+	if b == "" {
+		return a
+	}
+
+	return a + " " + b
+}
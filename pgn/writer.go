@@ -0,0 +1,223 @@
+package pgn
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ollybritton/StupidChess/position"
+)
+
+// maxLineLength is the longest a line of movetext can be in export format.
+const maxLineLength = 79
+
+// Writer writes games in PGN export format, the strict form of PGN meant to be read by other programs. Moves are always
+// written in proper SAN, tags in the Seven Tag Roster come first and in order, and movetext lines are wrapped.
+type Writer struct {
+	out io.Writer
+}
+
+// NewWriter returns a new Writer writing games to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{out: w}
+}
+
+// Write writes a single game, followed by a blank line to separate it from the next.
+func (w *Writer) Write(g *Game) error {
+	pos, err := g.StartingPosition()
+	if err != nil {
+		return err
+	}
+
+	var out strings.Builder
+
+	for _, tag := range exportTags(g) {
+		fmt.Fprintf(&out, "[%s \"%s\"]\n", tag.Name, escapeTagValue(tag.Value))
+	}
+
+	out.WriteString("\n")
+
+	movetext := &movetextWriter{}
+	movetext.comment(g.Comment)
+
+	if err := movetext.line(pos, g.Moves); err != nil {
+		return err
+	}
+
+	result := g.Result
+	if result == "" {
+		result = ResultUnknown
+	}
+
+	movetext.write(result)
+
+	out.WriteString(movetext.String())
+	out.WriteString("\n\n")
+
+	_, err = io.WriteString(w.out, out.String())
+	return err
+}
+
+// String returns the game in PGN export format.
+func (g *Game) String() string {
+	var out strings.Builder
+
+	if err := NewWriter(&out).Write(g); err != nil {
+		return fmt.Sprintf("invalid game: %s", err)
+	}
+
+	return out.String()
+}
+
+// exportTags returns the tags of a game in export order: the Seven Tag Roster, followed by every other tag sorted by
+// name. Missing tags in the Seven Tag Roster are filled in with unknown values, and the "Result" tag always matches the
+// result in the movetext.
+func exportTags(g *Game) []Tag {
+	tags := []Tag{}
+
+	for _, name := range sevenTagRoster {
+		value := g.Tag(name)
+
+		switch {
+		case name == "Result":
+			value = g.Result
+			if value == "" {
+				value = ResultUnknown
+			}
+
+		case value == "" && name == "Date":
+			value = "????.??.??"
+
+		case value == "":
+			value = "?"
+		}
+
+		tags = append(tags, Tag{Name: name, Value: value})
+	}
+
+	others := []Tag{}
+
+	for _, tag := range g.Tags {
+		if !isSevenTagRoster(tag.Name) {
+			others = append(others, tag)
+		}
+	}
+
+	sort.SliceStable(others, func(i, j int) bool {
+		return others[i].Name < others[j].Name
+	})
+
+	return append(tags, others...)
+}
+
+func isSevenTagRoster(name string) bool {
+	for _, roster := range sevenTagRoster {
+		if name == roster {
+			return true
+		}
+	}
+
+	return false
+}
+
+func escapeTagValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return strings.ReplaceAll(value, `"`, `\"`)
+}
+
+// movetextWriter builds the movetext of a game, wrapping lines so they don't exceed maxLineLength.
+type movetextWriter struct {
+	strings.Builder
+	lineLength int
+	afterOpen  bool // afterOpen is true when the last token opened a variation.
+}
+
+// write adds a single token to the movetext, separated from the previous token by a space or a newline. Parentheses
+// around variations aren't separated from the moves inside them.
+func (m *movetextWriter) write(token string) {
+	separator := " "
+	if m.Len() == 0 || m.afterOpen || token == ")" {
+		separator = ""
+	}
+
+	if m.Len() > 0 && m.lineLength+len(separator)+len(token) > maxLineLength {
+		m.WriteString("\n")
+		m.lineLength = 0
+		separator = ""
+	}
+
+	m.WriteString(separator)
+	m.WriteString(token)
+	m.lineLength += len(separator) + len(token)
+	m.afterOpen = token == "("
+}
+
+// comment adds a comment to the movetext if it isn't empty. Comments are split into words so that they can be wrapped
+// like the rest of the movetext.
+func (m *movetextWriter) comment(comment string) {
+	words := strings.Fields(strings.ReplaceAll(comment, "}", ""))
+	if len(words) == 0 {
+		return
+	}
+
+	words[0] = "{" + words[0]
+	words[len(words)-1] += "}"
+
+	for _, word := range words {
+		m.write(word)
+	}
+}
+
+// line adds a sequence of moves starting from the given position, including any variations. The position is left at
+// the end of the line.
+func (m *movetextWriter) line(pos *position.Position, moves []*Move) error {
+	// Black's moves only need a move number when something has interrupted the flow of the movetext, like the start of
+	// the line, a comment or a variation.
+	needNumber := true
+
+	for _, move := range moves {
+		if move.CommentBefore != "" {
+			m.comment(move.CommentBefore)
+			needNumber = true
+		}
+
+		if pos.SideToMove == position.White {
+			m.write(fmt.Sprintf("%d.", pos.FullMoves))
+		} else if needNumber {
+			m.write(fmt.Sprintf("%d...", pos.FullMoves))
+		}
+
+		san := pos.StringSAN(move.Move)
+		if !pos.MakeMove(move.Move) {
+			return fmt.Errorf("move %s is illegal in position %s", move.Move, pos.StringFEN())
+		}
+
+		m.write(san)
+		needNumber = false
+
+		for _, nag := range move.NAGs {
+			m.write(fmt.Sprintf("$%d", nag))
+		}
+
+		if move.CommentAfter != "" {
+			m.comment(move.CommentAfter)
+			needNumber = true
+		}
+
+		for _, variation := range move.Variations {
+			branch := pos.Copy()
+			branch.UndoMove(move.Move)
+
+			m.write("(")
+			if err := m.line(branch, variation); err != nil {
+				return err
+			}
+			m.write(")")
+
+			needNumber = true
+		}
+	}
+
+	return nil
+}
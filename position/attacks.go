@@ -0,0 +1,79 @@
+package position
+
+// AttackersTo returns the pieces of either color that attack the given square, using the given occupied squares rather
+// than those in the position to work out which sliding pieces are blocked. Passing the occupied squares with some
+// pieces taken off lets sliding pieces see through them, e.g. to check whether a king would still be attacked after
+// stepping away from a rook along the same rank.
+func (p *Position) AttackersTo(square uint8, occupied Bitboard) Bitboard {
+	rooks := p.Pieces[Rook] | p.Pieces[Queen]
+	bishops := p.Pieces[Bishop] | p.Pieces[Queen]
+
+	// A black pawn attacks the square if it is on one of the squares a white pawn on the square would attack, and the
+	// other way around.
+	return (pawnAttacks[White][square] & p.Pieces[Pawn] & p.Occupied[Black]) |
+		(pawnAttacks[Black][square] & p.Pieces[Pawn] & p.Occupied[White]) |
+		(knightMoves[square] & p.Pieces[Knight]) |
+		(kingMoves[square] & p.Pieces[King]) |
+		(rookAttacks(square, occupied) & rooks) |
+		(bishopAttacks(square, occupied) & bishops)
+}
+
+// Checkers returns the pieces giving check to the king of the side to move.
+func (p *Position) Checkers() Bitboard {
+	us := p.SideToMove
+	occupied := p.Occupied[White] | p.Occupied[Black]
+
+	return p.AttackersTo(p.KingLocation[us], occupied) & p.Occupied[us.Invert()]
+}
+
+// AttacksFrom returns the squares attacked by the piece on the given square, or an empty bitboard if there isn't one.
+func (p *Position) AttacksFrom(square uint8) Bitboard {
+	return PieceAttacks(p.Squares[square], square, p.Occupied[White]|p.Occupied[Black])
+}
+
+// AttacksBy returns every square attacked by the pieces of the given color, whether or not the square is occupied.
+func (p *Position) AttacksBy(color Color) Bitboard {
+	return p.attacksByPieces(p.Occupied[color])
+}
+
+// AttacksByPiece returns every square attacked by the pieces of the given color and type, e.g. every square attacked
+// by white's knights.
+func (p *Position) AttacksByPiece(color Color, piece Piece) Bitboard {
+	return p.attacksByPieces(p.Occupied[color] & p.Pieces[piece])
+}
+
+// attacksByPieces returns every square attacked by the pieces on the given squares.
+func (p *Position) attacksByPieces(pieces Bitboard) Bitboard {
+	occupied := p.Occupied[White] | p.Occupied[Black]
+
+	var attacks Bitboard
+
+	for pieces != 0 {
+		square := pieces.PopFirst()
+
+		attacks |= PieceAttacks(p.Squares[square], square, occupied)
+	}
+
+	return attacks
+}
+
+// PieceAttacks returns the squares a piece on the given square attacks, given the occupied squares on the board. For
+// pawns these are the squares it could capture on rather than the squares it can move to.
+func PieceAttacks(piece ColoredPiece, square uint8, occupied Bitboard) Bitboard {
+	switch piece.Colorless() {
+	case Pawn:
+		return pawnAttacks[piece.Color()][square]
+	case Knight:
+		return knightMoves[square]
+	case Bishop:
+		return bishopAttacks(square, occupied)
+	case Rook:
+		return rookAttacks(square, occupied)
+	case Queen:
+		return bishopAttacks(square, occupied) | rookAttacks(square, occupied)
+	case King:
+		return kingMoves[square]
+	}
+
+	return 0
+}
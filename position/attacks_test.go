@@ -0,0 +1,80 @@
+package position
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAttacks tests that the attack maps agree with IsAttacked and KingInCheck in random games.
+func TestAttacks(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for _, fen := range legalMovePositions {
+		pos, err := NewPositionFromFEN(fen)
+		assert.NoError(t, err)
+
+		for ply := 0; ply < 100; ply++ {
+			occupied := pos.Occupied[White] | pos.Occupied[Black]
+
+			for _, color := range [2]Color{White, Black} {
+				attacks := pos.AttacksBy(color)
+
+				for square := uint8(0); square < 64; square++ {
+					// IsAttacked also counts a pawn that can be taken en passant as attacked, which isn't an attack on
+					// its square.
+					if pos.HasEnPassant() && pos.Squares[square].Colorless() == Pawn && abs(int(square)-int(pos.EnPassant)) == 8 {
+						continue
+					}
+
+					expected := pos.IsAttacked(square, color)
+
+					assert.Equal(t, expected, attacks.IsOn(square), "AttacksBy(%d) on %s in %s", color, SquareToString(square), pos.StringFEN())
+					assert.Equal(t, expected, pos.AttackersTo(square, occupied)&pos.Occupied[color] != 0, "AttackersTo(%s) by %d in %s", SquareToString(square), color, pos.StringFEN())
+				}
+			}
+
+			for _, color := range [2]Color{White, Black} {
+				var byPiece Bitboard
+				for piece := Pawn; piece <= King; piece++ {
+					byPiece |= pos.AttacksByPiece(color, piece)
+				}
+
+				assert.Equal(t, pos.AttacksBy(color), byPiece, "AttacksByPiece(%d) in %s", color, pos.StringFEN())
+			}
+
+			assert.Equal(t, pos.KingInCheck(pos.SideToMove), pos.Checkers() != 0, "Checkers() in %s", pos.StringFEN())
+
+			moves := pos.MovesLegal().AsSlice()
+			if len(moves) == 0 {
+				break
+			}
+
+			pos.MakeMove(moves[rng.Intn(len(moves))])
+		}
+	}
+}
+
+// TestAttacksFrom tests the squares attacked by single pieces, including a pawn, which attacks diagonally rather than
+// where it moves, and sliding pieces blocked by other pieces.
+func TestAttacksFrom(t *testing.T) {
+	pos, err := NewPositionFromFEN("4k3/8/8/3p4/8/1B6/8/R3K3 w - - 0 1")
+	assert.NoError(t, err)
+
+	assert.Equal(t, squares(SquareC4, SquareE4), pos.AttacksFrom(SquareD5))
+	assert.Equal(t, squares(SquareA2, SquareA3, SquareA4, SquareA5, SquareA6, SquareA7, SquareA8, SquareB1, SquareC1, SquareD1, SquareE1), pos.AttacksFrom(SquareA1))
+	assert.Equal(t, squares(SquareA2, SquareA4, SquareC2, SquareD1, SquareC4, SquareD5), pos.AttacksFrom(SquareB3))
+	assert.Equal(t, Bitboard(0), pos.AttacksFrom(SquareH8))
+}
+
+// squares returns a bitboard with the given squares on.
+func squares(on ...uint8) Bitboard {
+	var bitboard Bitboard
+
+	for _, square := range on {
+		bitboard.On(square)
+	}
+
+	return bitboard
+}
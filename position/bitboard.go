@@ -8,6 +8,12 @@ import (
 	"unicode/utf8"
 )
 
+var (
+	// Pre-initialised tables of the diagonals through each square, in both directions.
+	diagonals     [64]Bitboard
+	antiDiagonals [64]Bitboard
+)
+
 type Bitboard uint64
 
 // On sets a specific bit on a bitboard on, i.e. to a 1.
@@ -81,6 +87,114 @@ func (b *Bitboard) LastOn() uint8 {
 	}
 }
 
+// PopFirst turns off the first bit that is on in the bitboard and returns its index. It is used to loop over the
+// squares in a bitboard:
+//
+//	for bitboard != 0 {
+//		square := bitboard.PopFirst()
+//	}
+func (b *Bitboard) PopFirst() uint8 {
+	square := b.FirstOn()
+	*b &= *b - 1
+
+	return square
+}
+
+// Count returns the number of bits that are on in the bitboard.
+func (b Bitboard) Count() int {
+	return bits.OnesCount64(uint64(b))
+}
+
+// Squares returns the indices of the bits that are on in the bitboard in order, so that they can be ranged over.
+func (b Bitboard) Squares() []uint8 {
+	squares := make([]uint8, 0, b.Count())
+
+	for b != 0 {
+		squares = append(squares, b.PopFirst())
+	}
+
+	return squares
+}
+
+// Shift moves every bit on the bitboard one square in the given direction, which is one of DirN, DirNE, DirE and so on.
+// Bits that would go off the edge of the board are dropped rather than wrapping around to the other side.
+func (b Bitboard) Shift(direction int) Bitboard {
+	switch direction {
+	case DirN:
+		return b << 8
+	case DirS:
+		return b >> 8
+	case DirE:
+		return (b &^ maskFileH) << 1
+	case DirW:
+		return (b &^ maskFileA) >> 1
+	case DirNE:
+		return (b &^ maskFileH) << 9
+	case DirNW:
+		return (b &^ maskFileA) << 7
+	case DirSE:
+		return (b &^ maskFileH) >> 7
+	case DirSW:
+		return (b &^ maskFileA) >> 9
+	}
+
+	return 0
+}
+
+// FileMask returns the squares on a file, numbered from 0 for the A-file to 7 for the H-file.
+func FileMask(file int) Bitboard {
+	return maskFileA << file
+}
+
+// RankMask returns the squares on a rank, numbered from 0 for the first rank to 7 for the eighth rank.
+func RankMask(rank int) Bitboard {
+	return maskRank1 << (8 * rank)
+}
+
+// DiagonalMask returns the squares on the diagonal running from the bottom left to the top right through the square.
+func DiagonalMask(square uint8) Bitboard {
+	return diagonals[square]
+}
+
+// AntiDiagonalMask returns the squares on the diagonal running from the top left to the bottom right through the
+// square.
+func AntiDiagonalMask(square uint8) Bitboard {
+	return antiDiagonals[square]
+}
+
+// Between returns the squares strictly between two squares on the same rank, file or diagonal, or an empty bitboard if
+// they aren't on the same line.
+func Between(from, to uint8) Bitboard {
+	return betweenSquares[from][to]
+}
+
+// Line returns every square on the rank, file or diagonal through two squares, from one edge of the board to the other,
+// or an empty bitboard if they aren't on the same line.
+func Line(from, to uint8) Bitboard {
+	return lineThrough[from][to]
+}
+
+// initialiseDiagonals sets up the tables of the diagonals through each square. Squares on the same diagonal have the
+// same difference between their rank and file, and squares on the same anti-diagonal have the same sum.
+func initialiseDiagonals() ([64]Bitboard, [64]Bitboard) {
+	diagonals := [64]Bitboard{}
+	antiDiagonals := [64]Bitboard{}
+
+	for square := 0; square < 64; square++ {
+		for other := 0; other < 64; other++ {
+			if square/8-square%8 == other/8-other%8 {
+				diagonals[square].On(uint8(other))
+			}
+
+			if square/8+square%8 == other/8+other%8 {
+				antiDiagonals[square].On(uint8(other))
+			}
+		}
+	}
+
+	return diagonals, antiDiagonals
+}
+
 func reverse(s string) string {
 	size := len(s)
 	buf := make([]byte, size)
@@ -91,3 +205,7 @@ func reverse(s string) string {
 	}
 	return string(buf)
 }
+
+func init() {
+	diagonals, antiDiagonals = initialiseDiagonals()
+}
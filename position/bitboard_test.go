@@ -27,3 +27,53 @@ func TestBitboardIsOn(t *testing.T) {
 	assert.Equal(t, true, bitboard.IsOn(14))
 	assert.Equal(t, false, bitboard.IsOn(0))
 }
+
+func TestBitboardPopFirst(t *testing.T) {
+	bitboard := Bitboard(0b00100000_00001000_00000000_00000000_00000000_00010000_01000000_00000000)
+	assert.Equal(t, 4, bitboard.Count())
+	assert.Equal(t, []uint8{14, 20, 51, 61}, bitboard.Squares())
+
+	assert.Equal(t, uint8(14), bitboard.PopFirst())
+	assert.Equal(t, uint8(20), bitboard.PopFirst())
+	assert.Equal(t, 2, bitboard.Count())
+
+	assert.Equal(t, 0, Bitboard(0).Count())
+	assert.Equal(t, []uint8{}, Bitboard(0).Squares())
+}
+
+func TestBitboardShift(t *testing.T) {
+	var bitboard Bitboard
+	bitboard.On(SquareA1)
+	bitboard.On(SquareH4)
+	bitboard.On(SquareD8)
+
+	tests := map[int][]uint8{
+		DirN:  {SquareA2, SquareH5},
+		DirS:  {SquareH3, SquareD7},
+		DirE:  {SquareB1, SquareE8},
+		DirW:  {SquareG4, SquareC8},
+		DirNE: {SquareB2},
+		DirNW: {SquareG5},
+		DirSE: {SquareE7},
+		DirSW: {SquareG3, SquareC7},
+	}
+
+	for direction, expected := range tests {
+		assert.Equal(t, expected, bitboard.Shift(direction).Squares(), "direction %d", direction)
+	}
+}
+
+func TestBitboardMasks(t *testing.T) {
+	assert.Equal(t, maskFileA, FileMask(0))
+	assert.Equal(t, maskFileH, FileMask(7))
+	assert.Equal(t, maskRank1, RankMask(0))
+	assert.Equal(t, maskRank8, RankMask(7))
+
+	assert.Equal(t, []uint8{SquareC1, SquareD2, SquareE3, SquareF4, SquareG5, SquareH6}, DiagonalMask(SquareE3).Squares())
+	assert.Equal(t, []uint8{SquareG1, SquareF2, SquareE3, SquareD4, SquareC5, SquareB6, SquareA7}, AntiDiagonalMask(SquareE3).Squares())
+	assert.Equal(t, []uint8{SquareA8}, DiagonalMask(SquareA8).Squares())
+
+	assert.Equal(t, []uint8{SquareC3, SquareD4}, Between(SquareB2, SquareE5).Squares())
+	assert.Equal(t, DiagonalMask(SquareB2), Line(SquareB2, SquareE5))
+	assert.Equal(t, Bitboard(0), Between(SquareB1, SquareE5))
+}
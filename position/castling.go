@@ -1,5 +1,7 @@
 package position
 
+import "fmt"
+
 // CastlingAvailability stores information about whether either player can castle in the current position.
 //   0b0000XYZW
 // X is black castling long.
@@ -49,22 +51,177 @@ func (c *CastlingAvailability) off(castlingType CastlingAvailability) {
 	(*c) &= ^castlingType
 }
 
-// castlingAvailabilityFromString returns a CastlingAvailability from a FEN-formatted castling string.
-func castlingAvailabilityFromString(str string) CastlingAvailability {
+// castlingRight returns the type of castling for the given side castling short (kingside) or long (queenside).
+func castlingRight(side Color, short bool) CastlingAvailability {
+	switch {
+	case side == White && short:
+		return shortW
+	case side == White:
+		return longW
+	case short:
+		return shortB
+	default:
+		return longB
+	}
+}
+
+// castlingIndex returns the index into Position.castlingRooks for a single type of castling.
+func castlingIndex(right CastlingAvailability) int {
+	switch right {
+	case shortW:
+		return 0
+	case longW:
+		return 1
+	case shortB:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// castlingTargets returns the squares the king and rook end up on after castling. These are the same as in standard
+// chess wherever the king and rook start: the king finishes on the g- or c-file and the rook next to it on the f- or
+// d-file.
+func castlingTargets(side Color, short bool) (king, rook uint8) {
+	backRank := uint8(0)
+	if side == Black {
+		backRank = 56
+	}
+
+	if short {
+		return backRank + 6, backRank + 5
+	}
+
+	return backRank + 2, backRank + 3
+}
+
+// defaultCastlingRooks are the starting squares of the castling rooks in standard chess.
+var defaultCastlingRooks = [4]uint8{SquareH1, SquareA1, SquareH8, SquareA8}
+
+// parseCastling parses the castling rights section of a FEN string, returning the castling availability and the
+// square of the rook for each type of castling. As well as the standard "KQkq", it understands Shredder-FEN, which
+// gives the file of each castling rook (e.g. "HAha"), and X-FEN, which uses "KQkq" for the outermost rook on each side
+// and the file only when it is an inner rook.
+//
+// For more information: https://www.chessprogramming.org/Forsyth-Edwards_Notation#Shredder-FEN
+func parseCastling(str string, squares [64]ColoredPiece, kingLocation [2]uint8) (CastlingAvailability, [4]uint8, error) {
 	var out CastlingAvailability
+	rooks := defaultCastlingRooks
+
+	if str == "-" {
+		return out, rooks, nil
+	}
 
 	for _, char := range str {
-		switch char {
-		case 'K':
-			out |= shortW
-		case 'Q':
-			out |= longW
-		case 'k':
-			out |= shortB
-		case 'q':
-			out |= longB
+		var side Color
+		var rook uint8
+
+		switch {
+		case char == 'K' || char == 'Q':
+			side = White
+			rook = outermostRook(squares, kingLocation[White], White, char == 'K')
+		case char == 'k' || char == 'q':
+			side = Black
+			rook = outermostRook(squares, kingLocation[Black], Black, char == 'k')
+		case char >= 'A' && char <= 'H':
+			side = White
+			rook = uint8(char - 'A')
+		case char >= 'a' && char <= 'h':
+			side = Black
+			rook = uint8(char-'a') + 56
+		default:
+			return 0, rooks, fmt.Errorf("invalid castling rights %q, can't understand %q", str, char)
+		}
+
+		right := castlingRight(side, rook%8 > kingLocation[side]%8)
+		out |= right
+		rooks[castlingIndex(right)] = rook
+	}
+
+	return out, rooks, nil
+}
+
+// outermostRook returns the square of the rook furthest from the king on the given side of it, which is the rook "K"
+// and "Q" refer to in X-FEN. If there is no rook, it returns the corner square.
+func outermostRook(squares [64]ColoredPiece, king uint8, side Color, short bool) uint8 {
+	backRank := king - king%8
+
+	if short {
+		for square := backRank + 7; square > king; square-- {
+			if squares[square] == Rook.OfColor(side) {
+				return square
+			}
+		}
+
+		return backRank + 7
+	}
+
+	for square := backRank; square < king; square++ {
+		if squares[square] == Rook.OfColor(side) {
+			return square
+		}
+	}
+
+	return backRank
+}
+
+// isCastle returns true if making the move would castle. As well as castling moves generated by the position, this
+// recognises castling in moves parsed without a position, which is written either as the king moving two squares or as
+// the king capturing its own rook.
+func (p *Position) isCastle(m Move, movingPiece ColoredPiece) bool {
+	if m.IsCastle() {
+		return true
+	}
+
+	if movingPiece.Colorless() != King {
+		return false
+	}
+
+	if p.Squares[m.To()] == Rook.OfColor(movingPiece.Color()) {
+		return true
+	}
+
+	return !p.Chess960 && abs(int(m.From())-int(m.To())) == 2
+}
+
+// castlingRightsOn returns the types of castling that use the rook starting on the given square. Moving a piece from or
+// to one of these squares means the rook has either moved or been taken, so that type of castling is no longer allowed.
+func (p *Position) castlingRightsOn(square uint8) CastlingAvailability {
+	var out CastlingAvailability
+
+	for i, right := range [4]CastlingAvailability{shortW, longW, shortB, longB} {
+		if p.castlingRooks[i] == square {
+			out |= right
 		}
 	}
 
 	return out
 }
+
+// castlingRook returns the square of the rook used by a castling move.
+func (p *Position) castlingRook(m Move, side Color) uint8 {
+	return p.castlingRooks[castlingIndex(castlingRight(side, m.To() > m.From()))]
+}
+
+// castle moves the king and rook for a castling move. Both are taken off the board before either is put back, since in
+// Chess960 each can finish on the square the other started on.
+func (p *Position) castle(m Move, side Color) {
+	rook := p.castlingRook(m, side)
+	kingTo, rookTo := castlingTargets(side, m.To() > m.From())
+
+	p.setSquare(m.From(), Empty)
+	p.setSquare(rook, Empty)
+	p.setSquare(kingTo, King.OfColor(side))
+	p.setSquare(rookTo, Rook.OfColor(side))
+}
+
+// uncastle undoes a castling move made by castle.
+func (p *Position) uncastle(m Move, side Color) {
+	rook := p.castlingRook(m, side)
+	kingTo, rookTo := castlingTargets(side, m.To() > m.From())
+
+	p.setSquare(kingTo, Empty)
+	p.setSquare(rookTo, Empty)
+	p.setSquare(m.From(), King.OfColor(side))
+	p.setSquare(rook, Rook.OfColor(side))
+}
@@ -0,0 +1,134 @@
+package position
+
+import (
+	"fmt"
+	"strings"
+)
+
+// chess960Knights are the squares the two knights go on out of the five left after placing the bishops and queen, for
+// each of the ten ways of placing them.
+var chess960Knights = [10][2]int{{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2}, {1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4}}
+
+// Chess960StartingPosition returns the FEN string for one of the 960 starting positions in Chess960, numbered from 0
+// to 959 as in Scharnagl's numbering scheme. Position 518 is the standard starting position.
+//
+// For more information: https://www.chessprogramming.org/Reinhard_Scharnagl#Chess960Numbering
+func Chess960StartingPosition(index int) (string, error) {
+	if index < 0 || index >= 960 {
+		return "", fmt.Errorf("invalid chess960 position %d, must be between 0 and 959", index)
+	}
+
+	backRank := [8]Piece{None, None, None, None, None, None, None, None}
+	n := index
+
+	// The bishops go on opposite colours: first the one on a light square (b, d, f or h), then the one on a dark square
+	// (a, c, e or g).
+	backRank[n%4*2+1] = Bishop
+	n /= 4
+
+	backRank[n%4*2] = Bishop
+	n /= 4
+
+	// The queen and knights are then placed on the remaining empty squares.
+	placeOnEmpty(&backRank, n%6, Queen)
+	n /= 6
+
+	knights := chess960Knights[n]
+	placeOnEmpty(&backRank, knights[1], Knight)
+	placeOnEmpty(&backRank, knights[0], Knight)
+
+	// This leaves three squares, which always have the king in between the two rooks.
+	placeOnEmpty(&backRank, 0, Rook)
+	placeOnEmpty(&backRank, 0, King)
+	placeOnEmpty(&backRank, 0, Rook)
+
+	var white, black strings.Builder
+	for _, piece := range backRank {
+		white.WriteString(piece.OfColor(White).String())
+		black.WriteString(piece.OfColor(Black).String())
+	}
+
+	return fmt.Sprintf("%s/pppppppp/8/8/8/8/PPPPPPPP/%s w KQkq - 0 1", black.String(), white.String()), nil
+}
+
+// placeOnEmpty places a piece on the nth empty square of the back rank, counting from zero.
+func placeOnEmpty(backRank *[8]Piece, n int, piece Piece) {
+	for i := range backRank {
+		if backRank[i] != None {
+			continue
+		}
+
+		if n == 0 {
+			backRank[i] = piece
+			return
+		}
+
+		n--
+	}
+}
+
+// needsChess960 returns true if the position has a type of castling where the king or rook doesn't start on the same
+// square as in standard chess.
+func (p *Position) needsChess960() bool {
+	for i, right := range [4]CastlingAvailability{shortW, longW, shortB, longB} {
+		if p.Castling&right == 0 {
+			continue
+		}
+
+		side := White
+		king := SquareE1
+
+		if right&(shortB|longB) != 0 {
+			side = Black
+			king = SquareE8
+		}
+
+		if p.KingLocation[side] != king || p.castlingRooks[i] != defaultCastlingRooks[i] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// castlingString returns the castling rights in a FEN string. Outside of Chess960 this is the standard "KQkq". In
+// Chess960 the rights are written in X-FEN, or Shredder-FEN if shredder is true.
+func (p *Position) castlingString(shredder bool) string {
+	if !p.Chess960 && !shredder {
+		return p.Castling.String()
+	}
+
+	var out strings.Builder
+
+	for i, right := range [4]CastlingAvailability{shortW, longW, shortB, longB} {
+		if p.Castling&right == 0 {
+			continue
+		}
+
+		side := White
+		if right&(shortB|longB) != 0 {
+			side = Black
+		}
+
+		short := right&(shortW|shortB) != 0
+		rook := p.castlingRooks[i]
+
+		if !shredder && rook == outermostRook(p.Squares, p.KingLocation[side], side, short) {
+			out.WriteString(right.String())
+			continue
+		}
+
+		file := fileChar(rook)
+		if side == White {
+			file -= 'a' - 'A'
+		}
+
+		out.WriteByte(file)
+	}
+
+	if out.Len() == 0 {
+		return "-"
+	}
+
+	return out.String()
+}
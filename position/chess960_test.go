@@ -0,0 +1,98 @@
+package position
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestChess960StartingPosition tests that starting positions are generated using Scharnagl's numbering.
+func TestChess960StartingPosition(t *testing.T) {
+	tests := map[int]string{
+		0:   "bbqnnrkr/pppppppp/8/8/8/8/PPPPPPPP/BBQNNRKR w KQkq - 0 1",
+		518: StartingPosition,
+		959: "rkrnnqbb/pppppppp/8/8/8/8/PPPPPPPP/RKRNNQBB w KQkq - 0 1",
+	}
+
+	for index, expected := range tests {
+		fen, err := Chess960StartingPosition(index)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, fen, "position %d", index)
+	}
+
+	_, err := Chess960StartingPosition(960)
+	assert.Error(t, err)
+}
+
+// TestChess960Perft tests move generation in Chess960 positions, including castling with the king or rook already on
+// its destination square. The expected results come from the Chess Programming Wiki:
+// https://www.chessprogramming.org/Chess960_Perft_Results
+func TestChess960Perft(t *testing.T) {
+	tests := []struct {
+		fen      string
+		expected []uint
+	}{
+		{"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", []uint{21, 528, 12189}},
+		{"2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9", []uint{21, 807, 18002}},
+		{"b1q1rrkb/pppppppp/3nn3/8/P7/1PPP4/4PPPP/BQNNRKRB w GE - 1 9", []uint{20, 479, 10471}},
+	}
+
+	for _, test := range tests {
+		pos, err := NewPositionFromFEN(test.fen)
+		assert.NoError(t, err)
+		assert.True(t, pos.Chess960, test.fen)
+
+		for i, expected := range test.expected {
+			assert.Equal(t, expected, pos.Perft(uint(i+1)), "%s at depth %d", test.fen, i+1)
+		}
+	}
+}
+
+// TestChess960FEN tests that castling rights are read from Shredder-FEN and X-FEN and written back in the same form.
+func TestChess960FEN(t *testing.T) {
+	tests := []struct {
+		in       string
+		xfen     string
+		shredder string
+	}{
+		{StartingPosition, StartingPosition, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w HAha - 0 1"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w HAha - 0 1", StartingPosition, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w HAha - 0 1"},
+		{"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w KQkq - 2 9", "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9"},
+		{"1k5r/8/8/8/8/8/8/1KR4R w C - 0 1", "1k5r/8/8/8/8/8/8/1KR4R w C - 0 1", "1k5r/8/8/8/8/8/8/1KR4R w C - 0 1"},
+	}
+
+	for _, test := range tests {
+		pos, err := NewPositionFromFEN(test.in)
+		assert.NoError(t, err)
+
+		assert.Equal(t, test.xfen, pos.StringFEN())
+		assert.Equal(t, test.shredder, pos.StringShredderFEN())
+	}
+
+	_, err := NewPositionFromFEN("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkx - 0 1")
+	assert.Error(t, err)
+}
+
+// TestChess960Castling tests that castling in Chess960 is written as the king capturing its own rook, and that it can
+// be made and undone.
+func TestChess960Castling(t *testing.T) {
+	fen := "1k5r/8/8/8/8/8/8/1KR4R w C - 0 1"
+
+	pos, err := NewPositionFromFEN(fen)
+	assert.NoError(t, err)
+
+	castle, err := pos.ParseMove("b1c1")
+	assert.NoError(t, err)
+	assert.True(t, castle.IsCastle())
+	assert.Equal(t, "O-O", pos.StringSAN(castle))
+
+	assert.True(t, pos.MakeMove(castle))
+	assert.Equal(t, WhiteKing, pos.Squares[SquareG1])
+	assert.Equal(t, WhiteRook, pos.Squares[SquareF1])
+	assert.Equal(t, Empty, pos.Squares[SquareB1])
+	assert.Equal(t, Empty, pos.Squares[SquareC1])
+	assert.Equal(t, CastlingAvailability(0), pos.Castling)
+
+	pos.UndoMove(castle)
+	assert.Equal(t, fen, pos.StringFEN())
+}
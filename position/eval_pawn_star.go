@@ -2,23 +2,13 @@ package position
 
 // EvalPawnStarUs evaluates the position from the perspective of the engine that really, really cares about its own pawns.
 func EvalPawnStarUs(pos *Position) int16 {
-	overall := int16(0)
+	overall := EvalSimple(pos)
+	pawns := pos.Pieces[Pawn] & pos.Occupied[pos.SideToMove]
 
-	for i := 0; i < 64; i++ {
-		curr := pos.Squares[i]
-
-		switch curr.Color() {
-		case White:
-			overall += simpleEvalTable[curr.Colorless()]
-		case Black:
-			overall -= simpleEvalTable[curr.Colorless()]
-		}
-
-		if pos.SideToMove == White && curr == WhitePawn {
-			overall += 15
-		} else if pos.SideToMove == Black && curr == BlackPawn {
-			overall -= 15
-		}
+	if pos.SideToMove == White {
+		overall += 15 * int16(pawns.Count())
+	} else {
+		overall -= 15 * int16(pawns.Count())
 	}
 
 	return overall
@@ -26,23 +16,13 @@ func EvalPawnStarUs(pos *Position) int16 {
 
 // EvalPawnStarThem evaluates the position from the perspective of the player, that sort of wants the engine's pawns.
 func EvalPawnStarThem(pos *Position) int16 {
-	overall := int16(0)
-
-	for i := 0; i < 64; i++ {
-		curr := pos.Squares[i]
-
-		switch curr.Color() {
-		case White:
-			overall += simpleEvalTable[curr.Colorless()]
-		case Black:
-			overall -= simpleEvalTable[curr.Colorless()]
-		}
+	overall := EvalSimple(pos)
+	pawns := pos.Pieces[Pawn] & pos.Occupied[pos.SideToMove.Invert()]
 
-		if pos.SideToMove == White && curr == BlackPawn {
-			overall -= 5
-		} else if pos.SideToMove == Black && curr == WhitePawn {
-			overall += 5
-		}
+	if pos.SideToMove == White {
+		overall -= 5 * int16(pawns.Count())
+	} else {
+		overall += 5 * int16(pawns.Count())
 	}
 
 	return overall
@@ -10,19 +10,14 @@ var simpleEvalTable = map[Piece]int16{
 }
 
 // EvalSimple evaluates the position using a simple material count.
-// TODO: This could definitely be sped up by using bitboards instead of the pos.Squares.
 func EvalSimple(pos *Position) int16 {
 	overall := int16(0)
 
-	for i := 0; i < 64; i++ {
-		curr := pos.Squares[i]
+	for piece := Pawn; piece <= King; piece++ {
+		white := pos.Pieces[piece] & pos.Occupied[White]
+		black := pos.Pieces[piece] & pos.Occupied[Black]
 
-		switch curr.Color() {
-		case White:
-			overall += simpleEvalTable[curr.Colorless()]
-		case Black:
-			overall -= simpleEvalTable[curr.Colorless()]
-		}
+		overall += simpleEvalTable[piece] * int16(white.Count()-black.Count())
 	}
 
 	return overall
@@ -0,0 +1,439 @@
+package position
+
+var (
+	// Pre-initialised table of the squares attacked by a pawn of each color on each square.
+	pawnAttacks [2][64]Bitboard
+
+	// Pre-initialised tables of the squares strictly between two squares on the same rank, file or diagonal, and of the
+	// whole line through them. Both are empty if the squares aren't on the same line.
+	betweenSquares [64][64]Bitboard
+	lineThrough    [64][64]Bitboard
+)
+
+// rookAttacks returns the squares a rook on the given square attacks, given the occupied squares on the board.
+func rookAttacks(square uint8, occupied Bitboard) Bitboard {
+	return rookMoves[square][magicIndex(rookMagics[square], rookMasks[square], occupied)]
+}
+
+// bishopAttacks returns the squares a bishop on the given square attacks, given the occupied squares on the board.
+func bishopAttacks(square uint8, occupied Bitboard) Bitboard {
+	return bishopMoves[square][magicIndex(bishopMagics[square], bishopMasks[square], occupied)]
+}
+
+// pinnedPieces returns the pieces of the given side that are pinned to their king, meaning they can only move along
+// the line between the king and the piece pinning them.
+func (p *Position) pinnedPieces(side Color) Bitboard {
+	king := p.KingLocation[side]
+	occupied := p.Occupied[White] | p.Occupied[Black]
+	them := p.Occupied[side.Invert()]
+
+	// Look for enemy sliders that would attack the king if there were only enemy pieces on the board, then check whether
+	// exactly one of our pieces is in the way.
+	pinners := (rookAttacks(king, them) & (p.Pieces[Rook] | p.Pieces[Queen]) & them) |
+		(bishopAttacks(king, them) & (p.Pieces[Bishop] | p.Pieces[Queen]) & them)
+
+	var pinned Bitboard
+
+	for pinners != 0 {
+		pinner := pinners.PopFirst()
+
+		blockers := betweenSquares[king][pinner] & occupied
+		if blockers.Count() == 1 && blockers&p.Occupied[side] != 0 {
+			pinned |= blockers
+		}
+	}
+
+	return pinned
+}
+
+// moveKind says which of the legal moves in a position to generate.
+type moveKind uint8
+
+const (
+	kindAll   moveKind = iota // kindAll generates every legal move.
+	kindNoisy                 // kindNoisy generates captures, including en passant, and promotions.
+	kindQuiet                 // kindQuiet generates every move that isn't a capture or a promotion, including castling.
+)
+
+// MovesCaptures generates the legal captures and promotions in a position, which are the moves searched by quiescence
+// and usually the first moves tried by the main search.
+func (p *Position) MovesCaptures() *MoveList {
+	moves := NewMoveList(16)
+	p.movesLegal(moves, kindNoisy)
+
+	return moves
+}
+
+// MovesQuiet generates the legal moves in a position that aren't captures or promotions. Together with MovesCaptures
+// this gives every legal move.
+func (p *Position) MovesQuiet() *MoveList {
+	moves := NewMoveList(48)
+	p.movesLegal(moves, kindQuiet)
+
+	return moves
+}
+
+// MovesEvasions generates the moves that get the side to move out of check. It is the same as MovesLegal, which
+// already only considers king moves, captures of the checking piece and blocks when in check, and is kept as a name
+// for the move picker to use when in check.
+func (p *Position) MovesEvasions() *MoveList {
+	moves := NewMoveList(16)
+	p.movesLegal(moves, kindAll)
+
+	return moves
+}
+
+// IsLegal returns true if the move is legal in the position. This is for moves that didn't come from the move
+// generator for this position, such as moves from the transposition table, where two positions with the same hash would
+// otherwise give a move that can't be made. The move must be exactly as the move generator would have created it,
+// including the pieces moved and captured and the castling and en passant state before the move.
+func (p *Position) IsLegal(m Move) bool {
+	us := p.SideToMove
+	from, to := m.From(), m.To()
+	piece := p.Squares[from]
+
+	if piece == Empty || piece.Color() != us {
+		return false
+	}
+
+	if m.IsCastle() {
+		if p.KingInCheck(us) {
+			return false
+		}
+
+		var castles MoveList
+		p.movesCastlingLegal(&castles)
+
+		for _, castle := range castles.Moves {
+			if castle.Equal(m) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if p.Squares[to] != Empty && p.Squares[to].Color() == us {
+		return false
+	}
+
+	captured := p.Squares[to]
+	occupied := p.Occupied[White] | p.Occupied[Black]
+
+	var targets Bitboard
+
+	switch piece.Colorless() {
+	case Pawn:
+		forward, startRank, lastRank := DirN, maskRank2, maskRank8
+		if us == Black {
+			forward, startRank, lastRank = DirS, maskRank7, maskRank1
+		}
+
+		if p.HasEnPassant() && to == p.EnPassant {
+			captured = Pawn.OfColor(us.Invert())
+		}
+
+		oneStep := uint8(int(from) + forward)
+		if !occupied.IsOn(oneStep) {
+			targets.On(oneStep)
+
+			twoSteps := uint8(int(oneStep) + forward)
+			if startRank.IsOn(from) && !occupied.IsOn(twoSteps) {
+				targets.On(twoSteps)
+			}
+		}
+
+		if captured != Empty {
+			targets |= pawnAttacks[us][from]
+		}
+
+		switch promotion := m.Promotion(); {
+		case lastRank.IsOn(to) && (promotion == Pawn || promotion == King || promotion == None):
+			return false
+		case !lastRank.IsOn(to) && promotion != None:
+			return false
+		}
+	case Knight:
+		targets = knightMoves[from]
+	case Bishop:
+		targets = bishopAttacks(from, occupied)
+	case Rook:
+		targets = rookAttacks(from, occupied)
+	case Queen:
+		targets = bishopAttacks(from, occupied) | rookAttacks(from, occupied)
+	case King:
+		targets = kingMoves[from]
+	}
+
+	if piece.Colorless() != Pawn && m.Promotion() != None {
+		return false
+	}
+
+	if !targets.IsOn(to) || !m.Equal(NewMove(from, to, piece, captured, m.Promotion(), p.Castling, p.EnPassant)) {
+		return false
+	}
+
+	if !p.MakeMove(m) {
+		return false
+	}
+
+	p.Unmake()
+
+	return true
+}
+
+// movesLegal generates only the legal moves in a position, without making any of them. The pieces giving check and the
+// pieces pinned to the king are worked out first:
+//   - in double check, only the king can move
+//   - in single check, other pieces can only capture the checking piece or block the check
+//   - pinned pieces can only move along the line between the king and the piece pinning them
+//
+// King moves are checked by looking for attackers with the king taken off the board, so that the king can't step back
+// along the line of a check. En passant captures are checked by making them on a copy of the occupied squares, since
+// taking both pawns off the rank can expose the king to a rook or queen.
+func (p *Position) movesLegal(moves *MoveList, kind moveKind) {
+	us := p.SideToMove
+	them := us.Invert()
+	king := p.KingLocation[us]
+	kingPiece := King.OfColor(us)
+
+	occupied := p.Occupied[White] | p.Occupied[Black]
+	checkers := p.Checkers()
+
+	// Squares pieces other than pawns can move to for the kind of moves being generated.
+	kindMask := ^p.Occupied[us]
+
+	switch kind {
+	case kindNoisy:
+		kindMask &= p.Occupied[them]
+	case kindQuiet:
+		kindMask &^= p.Occupied[them]
+	}
+
+	// King moves
+	withoutKing := occupied &^ (Bitboard(1) << king)
+	targets := kingMoves[king] & kindMask
+
+	for targets != 0 {
+		to := targets.PopFirst()
+
+		if p.AttackersTo(to, withoutKing)&p.Occupied[them] == 0 {
+			moves.Append(NewMove(king, to, kingPiece, p.Squares[to], None, p.Castling, p.EnPassant))
+		}
+	}
+
+	if checkers.Count() > 1 {
+		return
+	}
+
+	// Squares other pieces can move to: anywhere when not in check, otherwise capturing the checker or blocking.
+	allowed := ^p.Occupied[us]
+	if checkers != 0 {
+		allowed &= checkers | betweenSquares[king][checkers.FirstOn()]
+	} else if kind != kindNoisy {
+		p.movesCastlingLegal(moves)
+	}
+
+	pinned := p.pinnedPieces(us)
+
+	p.movesPawnsLegal(moves, kind, allowed, pinned)
+
+	allowed &= kindMask
+
+	pieces := p.Occupied[us] &^ p.Pieces[Pawn] &^ p.Pieces[King]
+
+	for pieces != 0 {
+		from := pieces.PopFirst()
+
+		piece := p.Squares[from]
+
+		var targets Bitboard
+
+		switch piece.Colorless() {
+		case Knight:
+			targets = knightMoves[from]
+		case Bishop:
+			targets = bishopAttacks(from, occupied)
+		case Rook:
+			targets = rookAttacks(from, occupied)
+		case Queen:
+			targets = bishopAttacks(from, occupied) | rookAttacks(from, occupied)
+		}
+
+		targets &= allowed
+
+		if pinned&(Bitboard(1)<<from) != 0 {
+			targets &= lineThrough[king][from]
+		}
+
+		for targets != 0 {
+			to := targets.PopFirst()
+
+			moves.Append(NewMove(from, to, piece, p.Squares[to], None, p.Castling, p.EnPassant))
+		}
+	}
+}
+
+// movesPawnsLegal generates the legal pawn moves of the given kind for the side to move, given the squares pieces are
+// allowed to move to because of check and the pinned pieces.
+func (p *Position) movesPawnsLegal(moves *MoveList, kind moveKind, allowed, pinned Bitboard) {
+	us := p.SideToMove
+	them := us.Invert()
+	king := p.KingLocation[us]
+	pawn := Pawn.OfColor(us)
+
+	occupied := p.Occupied[White] | p.Occupied[Black]
+
+	forward, startRank, lastRank := DirN, maskRank2, maskRank8
+	if us == Black {
+		forward, startRank, lastRank = DirS, maskRank7, maskRank1
+	}
+
+	pawns := p.Pieces[Pawn] & p.Occupied[us]
+
+	for pawns != 0 {
+		from := pawns.PopFirst()
+
+		var pushes Bitboard
+
+		oneStep := uint8(int(from) + forward)
+		if !occupied.IsOn(oneStep) {
+			pushes.On(oneStep)
+
+			twoSteps := uint8(int(oneStep) + forward)
+			if startRank.IsOn(from) && !occupied.IsOn(twoSteps) {
+				pushes.On(twoSteps)
+			}
+		}
+
+		captures := pawnAttacks[us][from] & p.Occupied[them]
+
+		// Pushes are only noisy if they promote, and captures are always noisy.
+		switch kind {
+		case kindNoisy:
+			pushes &= lastRank
+		case kindQuiet:
+			pushes &^= lastRank
+			captures = 0
+		}
+
+		targets := (pushes | captures) & allowed
+
+		if pinned.IsOn(from) {
+			targets &= lineThrough[king][from]
+		}
+
+		for targets != 0 {
+			to := targets.PopFirst()
+
+			if lastRank.IsOn(to) {
+				moves.Append(NewMove(from, to, pawn, p.Squares[to], Queen, p.Castling, p.EnPassant))
+				moves.Append(NewMove(from, to, pawn, p.Squares[to], Bishop, p.Castling, p.EnPassant))
+				moves.Append(NewMove(from, to, pawn, p.Squares[to], Knight, p.Castling, p.EnPassant))
+				moves.Append(NewMove(from, to, pawn, p.Squares[to], Rook, p.Castling, p.EnPassant))
+			} else {
+				moves.Append(NewMove(from, to, pawn, p.Squares[to], None, p.Castling, p.EnPassant))
+			}
+		}
+
+		// En passant captures take a pawn that isn't on the square moved to, so rather than use the masks they are
+		// checked by looking for attackers on the king after the capture.
+		if kind != kindQuiet && p.HasEnPassant() && pawnAttacks[us][from].IsOn(p.EnPassant) {
+			captured := uint8(int(p.EnPassant) - forward)
+			after := occupied&^(Bitboard(1)<<from)&^(Bitboard(1)<<captured) | (Bitboard(1) << p.EnPassant)
+
+			if p.AttackersTo(king, after)&p.Occupied[them]&^(Bitboard(1)<<captured) == 0 {
+				moves.Append(NewMove(from, p.EnPassant, pawn, Pawn.OfColor(them), None, p.Castling, p.EnPassant))
+			}
+		}
+	}
+}
+
+// movesCastlingLegal generates the legal castling moves for the side to move, which must not be in check. The squares
+// the king passes through are checked by movesCastling, but in Chess960 the rook moving can uncover an attack on the
+// square the king finishes on, so that is checked again with both pieces moved.
+func (p *Position) movesCastlingLegal(moves *MoveList) {
+	us := p.SideToMove
+	them := us.Invert()
+	occupied := p.Occupied[White] | p.Occupied[Black]
+
+	for _, short := range [2]bool{true, false} {
+		move, ok := p.castlingMove(short)
+		if !ok {
+			continue
+		}
+
+		rook := p.castlingRooks[castlingIndex(castlingRight(us, short))]
+		kingTo, rookTo := castlingTargets(us, short)
+
+		after := occupied &^ (Bitboard(1) << move.From()) &^ (Bitboard(1) << rook)
+		after |= (Bitboard(1) << kingTo) | (Bitboard(1) << rookTo)
+
+		if p.AttackersTo(kingTo, after)&p.Occupied[them] == 0 {
+			moves.Append(move)
+		}
+	}
+}
+
+// initialisePawnAttacks sets up the table of squares attacked by pawns.
+func initialisePawnAttacks() [2][64]Bitboard {
+	attacks := [2][64]Bitboard{}
+
+	for square := uint8(0); square < 64; square++ {
+		bitboard := Bitboard(1) << square
+
+		attacks[White][square] = (bitboard&^maskFileA)<<DirNW | (bitboard&^maskFileH)<<DirNE
+		attacks[Black][square] = (bitboard&^maskFileA)>>9 | (bitboard&^maskFileH)>>7
+	}
+
+	return attacks
+}
+
+// initialiseLines sets up the tables of squares between and through pairs of squares on the same line, by walking out
+// from every square in each of the eight directions.
+func initialiseLines() ([64][64]Bitboard, [64][64]Bitboard) {
+	between := [64][64]Bitboard{}
+	through := [64][64]Bitboard{}
+
+	directions := [8][2]int{{0, 1}, {1, 1}, {1, 0}, {1, -1}, {0, -1}, {-1, -1}, {-1, 0}, {-1, 1}}
+
+	for from := 0; from < 64; from++ {
+		for _, direction := range directions {
+			// The full line runs in both directions from the square.
+			line := Bitboard(1) << from
+
+			for _, sign := range [2]int{1, -1} {
+				file, rank := from%8+sign*direction[0], from/8+sign*direction[1]
+
+				for file >= 0 && file < 8 && rank >= 0 && rank < 8 {
+					line.On(uint8(rank*8 + file))
+
+					file += sign * direction[0]
+					rank += sign * direction[1]
+				}
+			}
+
+			var squares Bitboard
+			file, rank := from%8+direction[0], from/8+direction[1]
+
+			for file >= 0 && file < 8 && rank >= 0 && rank < 8 {
+				to := rank*8 + file
+
+				between[from][to] = squares
+				through[from][to] = line
+
+				squares.On(uint8(to))
+
+				file += direction[0]
+				rank += direction[1]
+			}
+		}
+	}
+
+	return between, through
+}
+
+func init() {
+	pawnAttacks = initialisePawnAttacks()
+	betweenSquares, lineThrough = initialiseLines()
+}
@@ -0,0 +1,255 @@
+package position
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"math/bits"
+	"math/rand"
+)
+
+//go:generate go run ../cmd/stupidchess magics --rook-bits 12 --bishop-bits 9 --tries 100000000 --seed 1 --out magic.go
+
+// magicTableEntry is the magic number for one square, which is multiplied by the blockers in the way of a rook or
+// bishop and shifted right to give an index into the table of moves for that square.
+type magicTableEntry struct {
+	multiplier uint64
+	shift      uint8
+}
+
+// magicIndex returns the index into the table of rook or bishop moves from a square, given the magic number and mask
+// for that square and the occupied squares on the board.
+func magicIndex(magic magicTableEntry, mask, occupied Bitboard) uint64 {
+	return (uint64(occupied&mask) * magic.multiplier) >> magic.shift
+}
+
+// Magics is a full set of magic numbers for rooks and bishops, as stored in magic.go.
+type Magics struct {
+	rook   [64]magicTableEntry
+	bishop [64]magicTableEntry
+
+	rookBits   int   // rookBits is the number of bits in the largest index into the table of rook moves.
+	bishopBits int   // bishopBits is the number of bits in the largest index into the table of bishop moves.
+	tries      int   // tries is the number of random numbers FindMagics was allowed to try for each square.
+	seed       int64 // seed is the seed FindMagics used for the random numbers it tried.
+}
+
+// MagicOptions controls the search for magic numbers done by FindMagics.
+type MagicOptions struct {
+	RookBits   int   // RookBits is the largest number of bits allowed in an index into the table of rook moves.
+	BishopBits int   // BishopBits is the largest number of bits allowed in an index into the table of bishop moves.
+	Tries      int   // Tries is how many random numbers to try for each square before giving up.
+	Seed       int64 // Seed seeds the random numbers tried, so that a search can be repeated.
+}
+
+// DefaultMagicOptions are the options the magic numbers in magic.go were found with.
+var DefaultMagicOptions = MagicOptions{RookBits: 12, BishopBits: 9, Tries: 100_000_000, Seed: 1}
+
+// CurrentMagics returns the magic numbers used for move generation, as stored in magic.go. They can be checked, but not
+// written out again, since the search that found them isn't known.
+func CurrentMagics() *Magics {
+	return &Magics{
+		rook:       rookMagics,
+		bishop:     bishopMagics,
+		rookBits:   rookIndexBits,
+		bishopBits: bishopIndexBits,
+	}
+}
+
+// FindMagics searches for a new set of magic numbers by trying random numbers for each square until one gives a
+// different index for every set of blockers that leads to different moves. Squares use an index with as many bits as
+// there are squares in their mask, unless that is more than the largest number of bits allowed, in which case finding
+// a magic number relies on blockers that lead to the same moves sharing an index.
+//
+// Every square has its own table with an entry for each possible index, so the limits are the only way to make the
+// tables of moves smaller. Smaller limits may need many more tries or be impossible, since the rook squares in the
+// corners have 12 squares in their mask. An error is returned for the first square that no magic number is found for.
+func FindMagics(options MagicOptions) (*Magics, error) {
+	if options.RookBits < 1 || options.RookBits > 12 || options.BishopBits < 1 || options.BishopBits > 9 {
+		return nil, fmt.Errorf("index bits must be between 1 and 12 for rooks and 1 and 9 for bishops, got %d and %d", options.RookBits, options.BishopBits)
+	}
+
+	rng := rand.New(rand.NewSource(options.Seed))
+
+	magics := &Magics{
+		rookBits:   options.RookBits,
+		bishopBits: options.BishopBits,
+		tries:      options.Tries,
+		seed:       options.Seed,
+	}
+
+	for square := uint8(0); square < 64; square++ {
+		rook, ok := findMagic(square, rookMasks[square], getRookMovesFromOccupationSlow, options.RookBits, options.Tries, rng)
+		if !ok {
+			return nil, fmt.Errorf("no rook magic found for %s with %d bits after %d tries", SquareToString(square), options.RookBits, options.Tries)
+		}
+
+		bishop, ok := findMagic(square, bishopMasks[square], getBishopMovesFromOccupationSlow, options.BishopBits, options.Tries, rng)
+		if !ok {
+			return nil, fmt.Errorf("no bishop magic found for %s with %d bits after %d tries", SquareToString(square), options.BishopBits, options.Tries)
+		}
+
+		magics.rook[square] = rook
+		magics.bishop[square] = bishop
+	}
+
+	return magics, nil
+}
+
+// findMagic searches for a magic number for a single square.
+func findMagic(square uint8, mask Bitboard, slow func(uint8, Bitboard) Bitboard, maxBits int, tries int, rng *rand.Rand) (magicTableEntry, bool) {
+	indexBits := mask.Count()
+	if indexBits > maxBits {
+		indexBits = maxBits
+	}
+
+	blockers, moves := magicSubsets(square, mask, slow)
+
+	// Rather than clearing the table for every number tried, each entry records which try it was filled in on.
+	table := make([]Bitboard, 1<<indexBits)
+	filled := make([]int, 1<<indexBits)
+
+	for try := 1; try <= tries; try++ {
+		// Magic numbers with only a few bits on work best.
+		magic := magicTableEntry{
+			multiplier: rng.Uint64() & rng.Uint64() & rng.Uint64(),
+			shift:      uint8(64 - indexBits),
+		}
+
+		// Numbers that don't spread the mask into the top bits of the index are never magic, so skip them quickly.
+		if bits.OnesCount64((uint64(mask)*magic.multiplier)&0xFF00000000000000) < 6 {
+			continue
+		}
+
+		ok := true
+
+		for i, occupied := range blockers {
+			index := magicIndex(magic, mask, occupied)
+
+			if filled[index] != try {
+				filled[index] = try
+				table[index] = moves[i]
+			} else if table[index] != moves[i] {
+				ok = false
+				break
+			}
+		}
+
+		if ok {
+			return magic, true
+		}
+	}
+
+	return magicTableEntry{}, false
+}
+
+// magicSubsets returns every set of blockers in a mask, and the moves from the square with each one.
+func magicSubsets(square uint8, mask Bitboard, slow func(uint8, Bitboard) Bitboard) ([]Bitboard, []Bitboard) {
+	blockers := make([]Bitboard, 0, 1<<mask.Count())
+	moves := make([]Bitboard, 0, 1<<mask.Count())
+
+	// This enumerates every subset of the mask, using the same trick as initialiseRookMoves.
+	subset := Bitboard(0)
+	for {
+		blockers = append(blockers, subset)
+		moves = append(moves, slow(square, subset))
+
+		subset = (subset - mask) & mask
+		if subset == 0 {
+			break
+		}
+	}
+
+	return blockers, moves
+}
+
+// Verify checks that the magic numbers give the right moves for every set of blockers from every square, by checking
+// them against the slow way of working out moves.
+func (m *Magics) Verify() error {
+	pieces := []struct {
+		name   string
+		magics [64]magicTableEntry
+		masks  [64]Bitboard
+		slow   func(uint8, Bitboard) Bitboard
+		bits   int
+	}{
+		{"rook", m.rook, rookMasks, getRookMovesFromOccupationSlow, m.rookBits},
+		{"bishop", m.bishop, bishopMasks, getBishopMovesFromOccupationSlow, m.bishopBits},
+	}
+
+	for _, piece := range pieces {
+		for square := uint8(0); square < 64; square++ {
+			magic := piece.magics[square]
+			if magic.shift < uint8(64-piece.bits) {
+				return fmt.Errorf("%s magic for %s needs %d bits, but the table only has %d", piece.name, SquareToString(square), 64-magic.shift, piece.bits)
+			}
+
+			mask := piece.masks[square]
+			blockers, moves := magicSubsets(square, mask, piece.slow)
+			table := make(map[uint64]Bitboard, len(blockers))
+
+			for i, occupied := range blockers {
+				index := magicIndex(magic, mask, occupied)
+
+				if existing, ok := table[index]; ok && existing != moves[i] {
+					return fmt.Errorf("%s magic for %s gives the same index for blockers with different moves", piece.name, SquareToString(square))
+				}
+
+				table[index] = moves[i]
+			}
+		}
+	}
+
+	return nil
+}
+
+// TableSize returns the size in bytes of the tables of rook and bishop moves needed for the magic numbers. Every square
+// has a table with an entry for each index, so this only depends on the number of bits in the largest index.
+func (m *Magics) TableSize() int {
+	return 64 * 8 * ((1 << m.rookBits) + (1 << m.bishopBits))
+}
+
+// WriteSource writes the Go source for magic.go using magic numbers found by FindMagics. The source starts with the
+// command that finds the same numbers again.
+func (m *Magics) WriteSource(w io.Writer) error {
+	var out bytes.Buffer
+
+	fmt.Fprintf(
+		&out,
+		"// Code generated by \"stupidchess magics --rook-bits %d --bishop-bits %d --tries %d --seed %d\"; DO NOT EDIT.\n",
+		m.rookBits,
+		m.bishopBits,
+		m.tries,
+		m.seed,
+	)
+	fmt.Fprintln(&out)
+	fmt.Fprintln(&out, "package position")
+	fmt.Fprintln(&out)
+	fmt.Fprintln(&out, "const (")
+	fmt.Fprintf(&out, "\trookIndexBits = %d // rookIndexBits is the number of bits in the largest index into rookMoves.\n", m.rookBits)
+	fmt.Fprintf(&out, "\tbishopIndexBits = %d // bishopIndexBits is the number of bits in the largest index into bishopMoves.\n", m.bishopBits)
+	fmt.Fprintln(&out, ")")
+
+	for _, table := range []struct {
+		name   string
+		magics [64]magicTableEntry
+	}{{"rookMagics", m.rook}, {"bishopMagics", m.bishop}} {
+		fmt.Fprintln(&out)
+		fmt.Fprintf(&out, "var %s = [64]magicTableEntry{\n", table.name)
+
+		for _, magic := range table.magics {
+			fmt.Fprintf(&out, "\t{\n\t\t0x%016X,\n\t\t%d,\n\t},\n", magic.multiplier, magic.shift)
+		}
+
+		fmt.Fprintln(&out, "}")
+	}
+
+	source, err := format.Source(out.Bytes())
+	if err != nil {
+		return fmt.Errorf("error formatting magic numbers: %w", err)
+	}
+
+	_, err = w.Write(source)
+	return err
+}
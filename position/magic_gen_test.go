@@ -0,0 +1,51 @@
+package position
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCurrentMagics tests that the magic numbers in use are correct and that magic.go is exactly what the generator
+// writes when run with the default options, so that "go generate" doesn't change it.
+func TestCurrentMagics(t *testing.T) {
+	current := CurrentMagics()
+	assert.NoError(t, current.Verify())
+
+	magics, err := FindMagics(DefaultMagicOptions)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, current.rook, magics.rook)
+	assert.Equal(t, current.bishop, magics.bishop)
+
+	var source bytes.Buffer
+	assert.NoError(t, magics.WriteSource(&source))
+
+	expected, err := os.ReadFile("magic.go")
+	assert.NoError(t, err)
+	assert.Equal(t, string(expected), source.String())
+}
+
+// TestFindMagics tests that the magic numbers found by searching are correct, and that the search gives up when the
+// tables are too small.
+func TestFindMagics(t *testing.T) {
+	magics, err := FindMagics(MagicOptions{RookBits: 12, BishopBits: 9, Tries: 10_000_000, Seed: 1})
+	if assert.NoError(t, err) {
+		assert.NoError(t, magics.Verify())
+	}
+
+	_, err = FindMagics(MagicOptions{RookBits: 4, BishopBits: 9, Tries: 1000, Seed: 1})
+	assert.Error(t, err)
+
+	_, err = FindMagics(MagicOptions{RookBits: 13, BishopBits: 9, Tries: 1000, Seed: 1})
+	assert.Error(t, err)
+
+	// A magic number that gives the same index for blockers with different moves is caught.
+	magics = CurrentMagics()
+	magics.rook[SquareD4].multiplier = 1
+	assert.Error(t, magics.Verify())
+}
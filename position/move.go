@@ -15,6 +15,7 @@ const (
 	maskPromotion = 0x00000000_00f00000 // 0000 0000  1111 0000  0000 0000  0000 0000
 	maskEnPassant = 0x00000000_0f000000 // 0000 1111  0000 0000  0000 0000  0000 0000
 	maskCastling  = 0x00000000_f0000000 // 1111 0000  0000 0000  0000 0000  0000 0000
+	maskIsCastle  = 0x00000001_00000000 // Only bit 32, which is set for castling moves.
 
 	maskEval = 0xffff0000_00000000
 
@@ -57,13 +58,28 @@ func NewMove(from, to uint8,
 			(uint64(priorCastling) << shiftCastling))
 }
 
+// NewCastlingMove returns a new castling move for the king on the from square. In standard chess the to square is the
+// square the king moves to, e.g. e1g1. In Chess960 it is the square of the rook the king is castling with, e.g. e1h1,
+// since otherwise castling can look the same as an ordinary king move.
+func NewCastlingMove(from, to uint8, king ColoredPiece, priorCastling CastlingAvailability, priorEnPassant uint8) Move {
+	return NewMove(from, to, king, Empty, None, priorCastling, priorEnPassant) | maskIsCastle
+}
+
 // SetEval sets the score for a move.
 func (m *Move) SetEval(score int16) {
 	(*m) &= ^Move(maskEval)
 	(*m) |= Move(uint16(score)) << shiftEval
 }
 
+// Equal returns true if two moves are the same, ignoring any score that has been set using SetEval.
+func (m Move) Equal(other Move) bool {
+	return m&^maskEval == other&^maskEval
+}
+
 // ParseMove parses a UCI-style long algebraic notation move into a Move.
+// Since it doesn't know anything about the position the move is played in, the moved and captured pieces are left as
+// Empty and the prior castling and en passant state is left blank. Use Position.ParseMove to get a move that can be
+// made and undone.
 func ParseMove(str string) (Move, error) {
 	var fromString, toString string
 	var promotion Piece = None
@@ -76,17 +92,32 @@ func ParseMove(str string) (Move, error) {
 	case 5:
 		fromString = str[0:2]
 		toString = str[2:4]
-		promotionString := string(str[4])
-		promotion = strToPiece(promotionString)
+
+		switch str[4] {
+		case 'n', 'b', 'r', 'q':
+			promotion = strToPiece(string(str[4]))
+		default:
+			return Move(0), fmt.Errorf("invalid move %q, can't promote to %q", str, str[4])
+		}
 
 	default:
-		return Move(0), fmt.Errorf("invalid move")
+		return Move(0), fmt.Errorf("invalid move %q, expecting a from square, a to square and an optional promotion, e.g. e7e8q", str)
+
+	}
+
+	from, ok := stringSquareMap[fromString]
+	if !ok {
+		return Move(0), fmt.Errorf("invalid move %q, %q is not a square", str, fromString)
+	}
 
+	to, ok := stringSquareMap[toString]
+	if !ok {
+		return Move(0), fmt.Errorf("invalid move %q, %q is not a square", str, toString)
 	}
 
 	return NewMove(
-		StringToSquare(fromString),
-		StringToSquare(toString),
+		from,
+		to,
 		Empty,
 		Empty,
 		promotion,
@@ -120,6 +151,27 @@ func (m Move) Promotion() Piece {
 	return Piece(uint64(m&maskPromotion) >> shiftPromotion)
 }
 
+// IsCapture returns true if the move captures a piece, including en passant captures.
+func (m Move) IsCapture() bool {
+	return m.Captured() != Empty
+}
+
+// IsPromotion returns true if the move promotes a pawn.
+func (m Move) IsPromotion() bool {
+	return m.Promotion() != None
+}
+
+// IsEnPassant returns true if the move is a pawn capturing en passant.
+func (m Move) IsEnPassant() bool {
+	return m.Moved().Colorless() == Pawn && m.IsCapture() && m.To() == m.PriorEnPassantTarget()
+}
+
+// IsCastle returns true if the move is a king castling. Whether it is castling short or long can be found by comparing
+// the from and to squares, since the king moves towards the rook in both standard chess and Chess960.
+func (m Move) IsCastle() bool {
+	return m&maskIsCastle != 0
+}
+
 // PriorCastling returns the castling status prior to the move being completed.
 func (m Move) PriorCastling() CastlingAvailability {
 	return CastlingAvailability((m & maskCastling) >> shiftCastling)
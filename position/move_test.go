@@ -34,9 +34,10 @@ func TestParseMoveValid(t *testing.T) {
 // TestParseMoveInvalid tests that an invalid move will cause an error.
 func TestParseMoveInvalid(t *testing.T) {
 	tests := []string{
-		// "beans",
+		"beans",
 		"",
-		// "e9b1p",
+		"e9b1p",
+		"e7e8k",
 	}
 
 	for _, test := range tests {
@@ -59,3 +60,29 @@ func TestMoveEval(t *testing.T) {
 	assert.Equal(t, move.From(), SquareD2)
 	assert.Equal(t, move.To(), SquareD4)
 }
+
+// TestPositionParseMove tests that parsing a move in a position gives a move with all the information needed to undo it,
+// and that illegal moves are rejected.
+func TestPositionParseMove(t *testing.T) {
+	pos, err := NewPositionFromFEN("r3k2r/pbppqppp/np3n2/2b1p3/2B1P3/NP3N2/PBPPQPPP/R3K2R w KQkq - 4 8")
+	assert.NoError(t, err)
+
+	move, err := pos.ParseMove("f3e5")
+	assert.NoError(t, err)
+	assert.Equal(t, WhiteKnight, move.Moved())
+	assert.Equal(t, BlackPawn, move.Captured())
+	assert.Equal(t, pos.Castling, move.PriorCastling())
+
+	castle, err := pos.ParseMove("e1g1")
+	assert.NoError(t, err)
+
+	before := pos.StringFEN()
+	pos.MakeMove(castle)
+	pos.UndoMove(castle)
+	assert.Equal(t, before, pos.StringFEN(), "expected undoing a parsed move to restore the position")
+
+	for _, illegal := range []string{"e1e2", "e8g8", "d2d5", "a1a8", "e4e5q"} {
+		_, err := pos.ParseMove(illegal)
+		assert.Error(t, err, "expected %s to be illegal", illegal)
+	}
+}
@@ -13,8 +13,8 @@ const (
 
 	DirNE = +9
 	DirNW = +7
-	DirSE = -9
-	DirSW = -7
+	DirSE = -7
+	DirSW = -9
 )
 
 var (
@@ -27,13 +27,22 @@ var (
 	bishopMasks [64]Bitboard
 
 	// Pre-initialised tables of rook and bishop moves from a square given an index calculated using a magic number.
-	rookMoves   [64][4096]Bitboard
-	bishopMoves [64][512]Bitboard
+	rookMoves   [64][1 << rookIndexBits]Bitboard
+	bishopMoves [64][1 << bishopIndexBits]Bitboard
 )
 
-// MovesLegal generates all legal moves in a position. It does this by generating all pseudolegal moves and then checking
-// if it places the king in check.
+// MovesLegal generates all legal moves in a position. Unlike MovesPseudolegal, it works out which moves would leave the
+// king in check without making them, which is much faster than filtering the pseudolegal moves.
 func (p *Position) MovesLegal() *MoveList {
+	moves := NewMoveList(60)
+	p.movesLegal(moves, kindAll)
+
+	return moves
+}
+
+// movesLegalFiltered generates all legal moves in a position by generating all pseudolegal moves and then checking if
+// they place the king in check. It gives the same moves as MovesLegal, and is kept to check it against.
+func (p *Position) movesLegalFiltered() *MoveList {
 	moves := p.MovesPseudolegal()
 	moves.Filter(func(move Move) bool {
 		if p.MakeMove(move) {
@@ -49,16 +58,13 @@ func (p *Position) MovesLegal() *MoveList {
 
 // MovesLegalWithEvaluation generates all legal moves in a position and sorts them using the given evaluation function.
 func (p *Position) MovesLegalWithEvaluation(evaluator Evaluator) *MoveList {
-	moves := p.MovesPseudolegal()
+	moves := p.MovesLegal()
 	moves.FilterMap(func(move Move) (bool, Move) {
-		if p.MakeMove(move) {
-			move.SetEval(evaluator(p))
-			p.UndoMove(move)
+		p.MakeMove(move)
+		move.SetEval(evaluator(p))
+		p.UndoMove(move)
 
-			return true, move
-		}
-
-		return false, Move(0)
+		return true, move
 	})
 
 	moves.Sort()
@@ -321,55 +327,72 @@ func (p *Position) MovesKing(moves *MoveList) {
 func (p *Position) movesCastling() []Move {
 	moves := []Move{}
 
-	if p.SideToMove == White && !p.KingInCheck(White) {
-		// When castling long for white, C1 and D1 can't be occupied or attacked (since the king would move through check), and B1 can't be occupied.
-		if p.Castling&longW != 0 &&
-			(p.IsEmpty(SquareC1) && !p.IsAttacked(SquareC1, Black)) &&
-			(p.IsEmpty(SquareD1) && !p.IsAttacked(SquareD1, Black)) &&
-			(p.IsEmpty(SquareB1)) {
+	if p.KingInCheck(p.SideToMove) {
+		return moves
+	}
+
+	if move, ok := p.castlingMove(true); ok {
+		moves = append(moves, move)
+	}
 
-			moves = append(
-				moves,
-				NewMove(SquareE1, SquareC1, WhiteKing, Empty, None, p.Castling, p.EnPassant),
-			)
-		}
+	if move, ok := p.castlingMove(false); ok {
+		moves = append(moves, move)
+	}
 
-		// When castling short for white, F1 and G1 can't be occupied or attacked.
-		if p.Castling&shortW != 0 &&
-			(p.IsEmpty(SquareF1) && !p.IsAttacked(SquareF1, Black)) &&
-			(p.IsEmpty(SquareG1) && !p.IsAttacked(SquareG1, Black)) {
+	return moves
+}
 
-			moves = append(
-				moves,
-				NewMove(SquareE1, SquareG1, WhiteKing, Empty, None, p.Castling, p.EnPassant),
-			)
+// castlingMove returns the move for the side to move castling short or long, if it is allowed. This works for Chess960
+// as well as standard chess: every square between the king and where it finishes, and between the rook and where it
+// finishes, has to be empty apart from the king and rook themselves, and the king can't pass through an attacked
+// square.
+func (p *Position) castlingMove(short bool) (Move, bool) {
+	side := p.SideToMove
+	right := castlingRight(side, short)
+
+	if p.Castling&right == 0 {
+		return NoMove, false
+	}
+
+	king := p.KingLocation[side]
+	rook := p.castlingRooks[castlingIndex(right)]
+	kingTo, rookTo := castlingTargets(side, short)
+
+	if p.Squares[rook] != Rook.OfColor(side) {
+		return NoMove, false
+	}
+
+	for _, path := range [2][2]uint8{{king, kingTo}, {rook, rookTo}} {
+		from, to := path[0], path[1]
+		if from > to {
+			from, to = to, from
 		}
-	} else if p.SideToMove == Black && !p.KingInCheck(Black) {
-		// When castling long for black, C8 and D8 can't be occupied or attacked (since the king would move through check), and B8 can't be occupied.
-		if p.Castling&longB != 0 &&
-			(p.IsEmpty(SquareC8) && !p.IsAttacked(SquareC8, White)) &&
-			(p.IsEmpty(SquareD8) && !p.IsAttacked(SquareD8, White)) &&
-			(p.IsEmpty(SquareB8)) {
-
-			moves = append(
-				moves,
-				NewMove(SquareE8, SquareC8, BlackKing, Empty, None, p.Castling, p.EnPassant),
-			)
+
+		for square := from; square <= to; square++ {
+			if square != king && square != rook && !p.IsEmpty(square) {
+				return NoMove, false
+			}
 		}
+	}
 
-		// When castling short for black, F8 and G8 can't be occupied or attacked.
-		if p.Castling&shortB != 0 &&
-			(p.IsEmpty(SquareF8) && !p.IsAttacked(SquareF8, White)) &&
-			(p.IsEmpty(SquareG8) && !p.IsAttacked(SquareG8, White)) {
+	for square := king; square != kingTo; {
+		if square < kingTo {
+			square++
+		} else {
+			square--
+		}
 
-			moves = append(
-				moves,
-				NewMove(SquareE8, SquareG8, BlackKing, Empty, None, p.Castling, p.EnPassant),
-			)
+		if p.IsAttacked(square, side.Invert()) {
+			return NoMove, false
 		}
 	}
 
-	return moves
+	to := kingTo
+	if p.Chess960 {
+		to = rook
+	}
+
+	return NewCastlingMove(king, to, King.OfColor(side), p.Castling, p.EnPassant), true
 }
 
 // MovesKnights generates the knight moves for the current side to move by looking up the current square in a table pre-populated with all the
@@ -400,10 +423,9 @@ func (p *Position) MovesRooks(moves *MoveList) {
 
 	for from := rookLocations.FirstOn(); from < 64 && from <= rookLocations.LastOn(); from++ {
 		if rookLocations.IsOn(from) {
-			blockers := (p.Occupied[p.SideToMove.Invert()] | p.Occupied[p.SideToMove]) & rookMasks[from]
-			key := (uint64(blockers) * rookMagics[from].multiplier) >> rookMagics[from].shift
+			occupied := p.Occupied[p.SideToMove.Invert()] | p.Occupied[p.SideToMove]
+			available := rookAttacks(from, occupied) & ^p.Occupied[p.SideToMove]
 
-			available := rookMoves[from][key] & ^p.Occupied[p.SideToMove]
 			p.movesFromBitboard(
 				moves,
 				Rook.OfColor(p.SideToMove),
@@ -424,10 +446,8 @@ func (p *Position) MovesBishops(moves *MoveList) {
 
 	for from := bishopLocations.FirstOn(); from < 64 && from <= bishopLocations.LastOn(); from++ {
 		if bishopLocations.IsOn(from) {
-			blockers := (p.Occupied[p.SideToMove.Invert()] | p.Occupied[p.SideToMove]) & bishopMasks[from]
-			key := (uint64(blockers) * bishopMagics[from].multiplier) >> bishopMagics[from].shift
-
-			available := bishopMoves[from][key] & ^p.Occupied[p.SideToMove]
+			occupied := p.Occupied[p.SideToMove.Invert()] | p.Occupied[p.SideToMove]
+			available := bishopAttacks(from, occupied) & ^p.Occupied[p.SideToMove]
 
 			p.movesFromBitboard(
 				moves,
@@ -447,13 +467,9 @@ func (p *Position) MovesQueens(moves *MoveList) {
 
 	for from := queenLocations.FirstOn(); from < 64 && from <= queenLocations.LastOn(); from++ {
 		if queenLocations.IsOn(from) {
-			bishopBlockers := (p.Occupied[p.SideToMove.Invert()] | p.Occupied[p.SideToMove]) & bishopMasks[from]
-			bishopKey := (uint64(bishopBlockers) * bishopMagics[from].multiplier) >> bishopMagics[from].shift
-			bishopAvailable := bishopMoves[from][bishopKey] & ^p.Occupied[p.SideToMove]
-
-			rookBlockers := (p.Occupied[p.SideToMove.Invert()] | p.Occupied[p.SideToMove]) & rookMasks[from]
-			rookKey := (uint64(rookBlockers) * rookMagics[from].multiplier) >> rookMagics[from].shift
-			rookAvailable := rookMoves[from][rookKey] & ^p.Occupied[p.SideToMove]
+			occupied := p.Occupied[p.SideToMove.Invert()] | p.Occupied[p.SideToMove]
+			bishopAvailable := bishopAttacks(from, occupied) & ^p.Occupied[p.SideToMove]
+			rookAvailable := rookAttacks(from, occupied) & ^p.Occupied[p.SideToMove]
 
 			available := bishopAvailable | rookAvailable
 
@@ -474,10 +490,9 @@ func (p *Position) MovesQueens(moves *MoveList) {
 // that needs to be passed will subtract 8.
 // This function doesn't know anything about promotions.
 func (p *Position) movesFromBitboard(moves *MoveList, piece ColoredPiece, fromFunc func(uint8) uint8, bitboard Bitboard) {
-	for to := bitboard.FirstOn(); to <= bitboard.LastOn() && to != 64; to++ {
-		if bitboard.IsOn(to) {
-			moves.Append(NewMove(fromFunc(to), to, piece, p.Squares[to], None, p.Castling, p.EnPassant))
-		}
+	for bitboard != 0 {
+		to := bitboard.PopFirst()
+		moves.Append(NewMove(fromFunc(to), to, piece, p.Squares[to], None, p.Castling, p.EnPassant))
 	}
 }
 
@@ -784,8 +799,8 @@ func getRookMovesFromOccupationSlow(from uint8, occupiedSquares Bitboard) Bitboa
 }
 
 // initialiseRookMoves generates the rook moves table that is indexed by the calculated key from the blockers.
-func initialiseRookMoves() [64][4096]Bitboard {
-	moves := [64][4096]Bitboard{}
+func initialiseRookMoves() [64][1 << rookIndexBits]Bitboard {
+	moves := [64][1 << rookIndexBits]Bitboard{}
 
 	for from := uint8(0); from < 64; from++ {
 		subset := Bitboard(0)
@@ -798,9 +813,7 @@ func initialiseRookMoves() [64][4096]Bitboard {
 		// This sort of reverse-engineers the magic numbers to come up with all possible blocker bits so we can initialise the table more
 		// quickly.
 		for subset != 0 || i == 0 {
-			index := subset
-			index = index * Bitboard(rookMagics[from].multiplier)
-			index = index >> rookMagics[from].shift
+			index := magicIndex(rookMagics[from], rookMasks[from], subset)
 			moves[from][index] = getRookMovesFromOccupationSlow(from, subset)
 
 			subset = (subset - rookMasks[from]) & rookMasks[from]
@@ -988,8 +1001,8 @@ func getBishopMovesFromOccupationSlow(from uint8, occupiedSquares Bitboard) Bitb
 	return bitboard
 }
 
-func initialiseBishopMoves() [64][512]Bitboard {
-	moves := [64][512]Bitboard{}
+func initialiseBishopMoves() [64][1 << bishopIndexBits]Bitboard {
+	moves := [64][1 << bishopIndexBits]Bitboard{}
 
 	for from := uint8(0); from < 64; from++ {
 		subset := Bitboard(0)
@@ -1002,9 +1015,7 @@ func initialiseBishopMoves() [64][512]Bitboard {
 		// This sort of reverse-engineers the magic numbers to come up with all possible blocker bits so we can initialise the table more
 		// quickly.
 		for subset != 0 || i == 0 {
-			index := subset
-			index = index * Bitboard(bishopMagics[from].multiplier)
-			index = index >> bishopMagics[from].shift
+			index := magicIndex(bishopMagics[from], bishopMasks[from], subset)
 			moves[from][index] = getBishopMovesFromOccupationSlow(from, subset)
 
 			subset = (subset - bishopMasks[from]) & bishopMasks[from]
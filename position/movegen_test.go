@@ -1,6 +1,8 @@
 package position
 
 import (
+	"math/rand"
+	"sort"
 	"testing"
 	"time"
 
@@ -140,3 +142,131 @@ func testPosition(t *testing.T, name, positionFEN string, results []uint) {
 		)
 	}
 }
+
+// legalMovePositions are positions with checks, pins and en passant captures that are tricky to generate legal moves
+// for.
+var legalMovePositions = []string{
+	StartingPosition,
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+	"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+	"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+	"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+	"8/8/8/KPp4r/8/8/8/7k w - c6 0 2",    // Taking en passant uncovers a check along the rank.
+	"8/8/8/8/k2Pp2Q/8/8/3K4 b - d3 0 1",  // The same, for black.
+	"8/8/8/4k3/2pP4/8/8/3KB3 b - d3 0 1", // Taking en passant gets out of check from the pawn.
+	"4k3/8/8/b7/8/8/3R4/r3K2N w - - 0 1", // In check, with a pinned rook.
+	"4k3/8/8/8/8/5n2/8/r3K3 w - - 0 1",   // Double check.
+	"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9",
+}
+
+// TestMovesLegal tests that generating legal moves directly gives the same moves as filtering the pseudolegal moves, in
+// tricky positions and in random games.
+func TestMovesLegal(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for _, fen := range legalMovePositions {
+		pos, err := NewPositionFromFEN(fen)
+		assert.NoError(t, err)
+
+		for ply := 0; ply < 100; ply++ {
+			expected := pos.movesLegalFiltered().AsSlice()
+			got := pos.MovesLegal().AsSlice()
+
+			sort.Slice(expected, func(i, j int) bool { return expected[i] < expected[j] })
+			sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+
+			if !assert.Equal(t, expected, got, "legal moves in %s", pos.StringFEN()) || len(got) == 0 {
+				break
+			}
+
+			pos.MakeMove(got[rng.Intn(len(got))])
+		}
+	}
+}
+
+// BenchmarkMovesLegal measures generating legal moves directly.
+func BenchmarkMovesLegal(b *testing.B) {
+	benchmarkMoves(b, (*Position).MovesLegal)
+}
+
+// BenchmarkMovesLegalFiltered measures generating legal moves by making each pseudolegal move, for comparison with
+// BenchmarkMovesLegal.
+func BenchmarkMovesLegalFiltered(b *testing.B) {
+	benchmarkMoves(b, (*Position).movesLegalFiltered)
+}
+
+func benchmarkMoves(b *testing.B, generate func(*Position) *MoveList) {
+	positions := make([]*Position, 0, len(legalMovePositions))
+
+	for _, fen := range legalMovePositions {
+		pos, err := NewPositionFromFEN(fen)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		positions = append(positions, pos)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		generate(positions[i%len(positions)])
+	}
+}
+
+// TestMovesStaged tests that captures and quiet moves together give every legal move, that evasions give every legal
+// move when in check, and that IsLegal agrees with the move generator, including for moves from the previous position.
+func TestMovesStaged(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for _, fen := range legalMovePositions {
+		pos, err := NewPositionFromFEN(fen)
+		assert.NoError(t, err)
+
+		var previous []Move
+
+		for ply := 0; ply < 100; ply++ {
+			legal := pos.MovesLegal().AsSlice()
+			captures := pos.MovesCaptures().AsSlice()
+			quiet := pos.MovesQuiet().AsSlice()
+
+			for _, move := range captures {
+				assert.True(t, move.IsCapture() || move.IsPromotion(), "capture %s in %s", move, pos.StringFEN())
+			}
+
+			for _, move := range quiet {
+				assert.False(t, move.IsCapture() || move.IsPromotion(), "quiet move %s in %s", move, pos.StringFEN())
+			}
+
+			staged := append(captures, quiet...)
+
+			sort.Slice(legal, func(i, j int) bool { return legal[i] < legal[j] })
+			sort.Slice(staged, func(i, j int) bool { return staged[i] < staged[j] })
+
+			assert.Equal(t, legal, staged, "captures and quiet moves in %s", pos.StringFEN())
+
+			if pos.KingInCheck(pos.SideToMove) {
+				evasions := pos.MovesEvasions().AsSlice()
+				sort.Slice(evasions, func(i, j int) bool { return evasions[i] < evasions[j] })
+
+				assert.Equal(t, legal, evasions, "evasions in %s", pos.StringFEN())
+			}
+
+			isLegal := make(map[Move]bool, len(legal))
+			for _, move := range legal {
+				isLegal[move] = true
+			}
+
+			for _, move := range append(pos.MovesPseudolegal().AsSlice(), previous...) {
+				assert.Equal(t, isLegal[move], pos.IsLegal(move), "IsLegal(%s) in %s", move, pos.StringFEN())
+			}
+
+			if len(legal) == 0 {
+				break
+			}
+
+			previous = legal
+			pos.MakeMove(legal[rng.Intn(len(legal))])
+		}
+	}
+}
@@ -30,10 +30,9 @@ func (l *MoveList) AppendFromBitboard(piece ColoredPiece,
 	castling CastlingAvailability,
 	enPassantTarget uint8,
 ) {
-	for to := bitboard.FirstOn(); to <= bitboard.LastOn() && to != 64; to++ {
-		if bitboard.IsOn(to) {
-			l.Moves = append(l.Moves, (NewMove(fromFunc(to), to, piece, squares[to], None, castling, enPassantTarget)))
-		}
+	for bitboard != 0 {
+		to := bitboard.PopFirst()
+		l.Moves = append(l.Moves, (NewMove(fromFunc(to), to, piece, squares[to], None, castling, enPassantTarget)))
 	}
 }
 
@@ -0,0 +1,149 @@
+package position
+
+// Result is the result of a game, from the point of view of white.
+type Result uint8
+
+const (
+	ResultNone      Result = iota // ResultNone means the game isn't over.
+	ResultWhiteWins               // ResultWhiteWins means white has won.
+	ResultBlackWins               // ResultBlackWins means black has won.
+	ResultDraw                    // ResultDraw means the game is drawn.
+)
+
+// String returns the result as written at the end of a PGN game, e.g. "1-0" or "1/2-1/2", and "*" if the game isn't
+// over.
+func (r Result) String() string {
+	switch r {
+	case ResultWhiteWins:
+		return "1-0"
+	case ResultBlackWins:
+		return "0-1"
+	case ResultDraw:
+		return "1/2-1/2"
+	default:
+		return "*"
+	}
+}
+
+// Reason is the reason a game is over.
+type Reason uint8
+
+const (
+	ReasonNone                 Reason = iota // ReasonNone means the game isn't over.
+	ReasonCheckmate                          // ReasonCheckmate means the side to move is in check and has no legal moves.
+	ReasonStalemate                          // ReasonStalemate means the side to move isn't in check but has no legal moves.
+	ReasonInsufficientMaterial               // ReasonInsufficientMaterial means neither side has the pieces to checkmate.
+	ReasonFivefoldRepetition                 // ReasonFivefoldRepetition means the position has occurred five times.
+	ReasonSeventyFiveMoveRule                // ReasonSeventyFiveMoveRule means 75 moves have been made without a capture or pawn move.
+	ReasonThreefoldRepetition                // ReasonThreefoldRepetition means the position has occurred three times, and a draw can be claimed.
+	ReasonFiftyMoveRule                      // ReasonFiftyMoveRule means 50 moves have been made without a capture or pawn move, and a draw can be claimed.
+)
+
+// String returns a short description of the reason, e.g. "checkmate".
+func (r Reason) String() string {
+	switch r {
+	case ReasonCheckmate:
+		return "checkmate"
+	case ReasonStalemate:
+		return "stalemate"
+	case ReasonInsufficientMaterial:
+		return "insufficient material"
+	case ReasonFivefoldRepetition:
+		return "fivefold repetition"
+	case ReasonSeventyFiveMoveRule:
+		return "seventy-five move rule"
+	case ReasonThreefoldRepetition:
+		return "threefold repetition"
+	case ReasonFiftyMoveRule:
+		return "fifty move rule"
+	default:
+		return "none"
+	}
+}
+
+// Claimable returns true if the game only ends for this reason when one of the players claims a draw, rather than
+// ending straight away.
+func (r Reason) Claimable() bool {
+	return r == ReasonThreefoldRepetition || r == ReasonFiftyMoveRule
+}
+
+// Outcome is the result of a game and the reason for it.
+type Outcome struct {
+	Result Result // Result is who won the game, or ResultNone if it isn't over.
+	Reason Reason // Reason is why the game is over, or ReasonNone if it isn't.
+}
+
+// IsOver returns true if the game is over.
+func (o Outcome) IsOver() bool {
+	return o.Result != ResultNone
+}
+
+// String returns the result and the reason for it, e.g. "1-0 (checkmate)".
+func (o Outcome) String() string {
+	if !o.IsOver() {
+		return o.Result.String()
+	}
+
+	return o.Result.String() + " (" + o.Reason.String() + ")"
+}
+
+// Outcome returns whether the game is over, and if so, who won and why. Draws that have to be claimed, by threefold
+// repetition or the fifty move rule, are treated as if they have been claimed, which is what tournaments between
+// engines normally do; use Reason.Claimable to tell them apart. If the game is over for more than one reason, the one
+// that ends it straight away is given, so checkmate on the hundredth halfmove without a capture is still checkmate.
+//
+// Repetitions are only found among positions reached since the game started or was loaded from a FEN string.
+func (p *Position) Outcome() Outcome {
+	if p.MovesLegal().Len() == 0 {
+		if p.KingInCheck(p.SideToMove) {
+			if p.SideToMove == White {
+				return Outcome{ResultBlackWins, ReasonCheckmate}
+			}
+
+			return Outcome{ResultWhiteWins, ReasonCheckmate}
+		}
+
+		return Outcome{ResultDraw, ReasonStalemate}
+	}
+
+	if p.IsInsufficientMaterial() {
+		return Outcome{ResultDraw, ReasonInsufficientMaterial}
+	}
+
+	repetitions := p.RepetitionCount()
+
+	switch {
+	case repetitions >= 5:
+		return Outcome{ResultDraw, ReasonFivefoldRepetition}
+	case p.HalfmoveClock >= 150:
+		return Outcome{ResultDraw, ReasonSeventyFiveMoveRule}
+	case repetitions >= 3:
+		return Outcome{ResultDraw, ReasonThreefoldRepetition}
+	case p.IsFiftyMoveDraw():
+		return Outcome{ResultDraw, ReasonFiftyMoveRule}
+	}
+
+	return Outcome{ResultNone, ReasonNone}
+}
+
+// lightSquares has every light square on the board on, starting with B1.
+const lightSquares = Bitboard(0x55AA55AA55AA55AA)
+
+// IsInsufficientMaterial returns true if neither side can ever checkmate the other, whatever moves are played. This is
+// the case with only kings left, with a single knight or bishop against a bare king, and when every bishop left is on
+// squares of the same colour. Positions like king and knight against king and knight aren't included, since although
+// neither side can force checkmate, it can still happen.
+func (p *Position) IsInsufficientMaterial() bool {
+	if p.Pieces[Pawn]|p.Pieces[Rook]|p.Pieces[Queen] != 0 {
+		return false
+	}
+
+	knights := p.Pieces[Knight]
+	bishops := p.Pieces[Bishop]
+
+	if knights != 0 {
+		return bishops == 0 && knights.Count() == 1
+	}
+
+	return bishops&lightSquares == 0 || bishops&^lightSquares == 0
+}
@@ -0,0 +1,68 @@
+package position
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestOutcome tests that positions where the game is over are given the right result and reason.
+func TestOutcome(t *testing.T) {
+	tests := []struct {
+		fen  string
+		want Outcome
+	}{
+		{StartingPosition, Outcome{ResultNone, ReasonNone}},
+		{"rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3", Outcome{ResultBlackWins, ReasonCheckmate}},
+		{"6k1/5ppp/8/8/8/8/8/K2R4 b - - 0 1", Outcome{ResultNone, ReasonNone}},
+		{"3R2k1/5ppp/8/8/8/8/8/K7 b - - 0 1", Outcome{ResultWhiteWins, ReasonCheckmate}},
+		{"3R2k1/5ppp/8/8/8/8/8/K7 b - - 120 80", Outcome{ResultWhiteWins, ReasonCheckmate}},
+		{"k7/2Q5/1K6/8/8/8/8/8 b - - 0 1", Outcome{ResultDraw, ReasonStalemate}},
+		{"k7/8/1K6/8/8/8/8/8 w - - 0 1", Outcome{ResultDraw, ReasonInsufficientMaterial}},
+		{"k7/8/1K6/8/8/8/8/6N1 w - - 0 1", Outcome{ResultDraw, ReasonInsufficientMaterial}},
+		{"k7/8/1K6/8/8/8/8/5B2 w - - 0 1", Outcome{ResultDraw, ReasonInsufficientMaterial}},
+		{"k7/8/1K6/8/8/8/8/4bB2 w - - 0 1", Outcome{ResultNone, ReasonNone}},
+		{"k7/8/1K6/8/8/8/8/3b1B2 w - - 0 1", Outcome{ResultDraw, ReasonInsufficientMaterial}},
+		{"k7/8/1K6/8/8/8/2b5/3b1B2 w - - 0 1", Outcome{ResultDraw, ReasonInsufficientMaterial}},
+		{"k7/8/1K6/8/8/8/8/4nN2 w - - 0 1", Outcome{ResultNone, ReasonNone}},
+		{"k7/8/1K6/8/8/8/8/5NN1 w - - 0 1", Outcome{ResultNone, ReasonNone}},
+		{"k7/8/1K6/8/8/8/8/5BN1 w - - 0 1", Outcome{ResultNone, ReasonNone}},
+		{"k7/8/1K6/8/8/8/P7/8 w - - 0 1", Outcome{ResultNone, ReasonNone}},
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 99 80", Outcome{ResultNone, ReasonNone}},
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 100 80", Outcome{ResultDraw, ReasonFiftyMoveRule}},
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 150 80", Outcome{ResultDraw, ReasonSeventyFiveMoveRule}},
+	}
+
+	for _, test := range tests {
+		pos, err := NewPositionFromFEN(test.fen)
+		assert.NoError(t, err)
+		assert.Equal(t, test.want, pos.Outcome(), "wrong outcome for %s", test.fen)
+	}
+}
+
+// TestOutcomeRepetition tests that repeating a position three times is a claimable draw, and five times ends the game.
+func TestOutcomeRepetition(t *testing.T) {
+	pos, err := NewPositionFromFEN(StartingPosition)
+	assert.NoError(t, err)
+
+	want := map[int]Outcome{
+		2: {ResultNone, ReasonNone},
+		3: {ResultDraw, ReasonThreefoldRepetition},
+		4: {ResultDraw, ReasonThreefoldRepetition},
+		5: {ResultDraw, ReasonFivefoldRepetition},
+	}
+
+	for i := 2; i <= 5; i++ {
+		for _, move := range []string{"g1f3", "g8f6", "f3g1", "f6g8"} {
+			parsed, err := pos.ParseMove(move)
+			assert.NoError(t, err)
+
+			pos.MakeMove(parsed)
+		}
+
+		assert.Equal(t, want[i], pos.Outcome(), "wrong outcome after the position has occurred %d times", i)
+	}
+
+	assert.True(t, pos.Outcome().Reason == ReasonFivefoldRepetition && !pos.Outcome().Reason.Claimable())
+	assert.Equal(t, "1/2-1/2 (fivefold repetition)", pos.Outcome().String())
+}
@@ -0,0 +1,270 @@
+package position
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// PerftStats breaks down the games counted by perft by the kind of move made last, which narrows down where a bug in
+// the move generator is when the node count is wrong.
+//
+// For more information: https://www.chessprogramming.org/Perft_Results
+type PerftStats struct {
+	Nodes      uint // Nodes is the number of games, as returned by Perft.
+	Captures   uint // Captures is the number of games ending in a capture, including en passant captures.
+	EnPassant  uint // EnPassant is the number of games ending in an en passant capture.
+	Castles    uint // Castles is the number of games ending in castling.
+	Promotions uint // Promotions is the number of games ending in a promotion.
+	Checks     uint // Checks is the number of games ending in check, including checkmate.
+	Checkmates uint // Checkmates is the number of games ending in checkmate.
+}
+
+// Add adds the counts in other to the counts in s.
+func (s *PerftStats) Add(other PerftStats) {
+	s.Nodes += other.Nodes
+	s.Captures += other.Captures
+	s.EnPassant += other.EnPassant
+	s.Castles += other.Castles
+	s.Promotions += other.Promotions
+	s.Checks += other.Checks
+	s.Checkmates += other.Checkmates
+}
+
+// String returns the stats on a single line, e.g.
+//
+//	nodes 97862 captures 17102 e.p. 45 castles 3162 promotions 0 checks 993 checkmates 1
+func (s PerftStats) String() string {
+	return fmt.Sprintf(
+		"nodes %d captures %d e.p. %d castles %d promotions %d checks %d checkmates %d",
+		s.Nodes,
+		s.Captures,
+		s.EnPassant,
+		s.Castles,
+		s.Promotions,
+		s.Checks,
+		s.Checkmates,
+	)
+}
+
+// PerftExtended is like Perft, but also counts the kinds of move made at the last ply. This is slower than Perft since
+// it has to look for checks and checkmates.
+func (p *Position) PerftExtended(depth uint) PerftStats {
+	if depth == 0 {
+		return PerftStats{Nodes: 1}
+	}
+
+	var stats PerftStats
+
+	for _, move := range p.MovesPseudolegal().AsSlice() {
+		if !p.MakeMove(move) {
+			continue
+		}
+
+		if depth > 1 {
+			stats.Add(p.PerftExtended(depth - 1))
+		} else {
+			stats.Add(p.perftLeaf(move))
+		}
+
+		p.UndoMove(move)
+	}
+
+	return stats
+}
+
+// perftLeaf returns the stats for a single game ending in the given move, which must have just been made.
+func (p *Position) perftLeaf(move Move) PerftStats {
+	stats := PerftStats{Nodes: 1}
+
+	if move.IsCapture() {
+		stats.Captures++
+	}
+
+	if move.IsEnPassant() {
+		stats.EnPassant++
+	}
+
+	if move.IsCastle() {
+		stats.Castles++
+	}
+
+	if move.IsPromotion() {
+		stats.Promotions++
+	}
+
+	if p.KingInCheck(p.SideToMove) {
+		stats.Checks++
+
+		if p.MovesLegal().Len() == 0 {
+			stats.Checkmates++
+		}
+	}
+
+	return stats
+}
+
+// PerftOptions are the options for PerftParallel and PerftDivide.
+type PerftOptions struct {
+	Threads  int        // Threads is the number of goroutines the moves at the root are split between.
+	Bulk     bool       // Bulk counts the legal moves at the last ply rather than making each one.
+	Hash     *PerftHash // Hash stores the counts for positions that have already been seen, or is nil to disable it.
+	Extended bool       // Extended breaks the counts down like PerftExtended. Bulk counting and the hash aren't used.
+}
+
+// PerftParallel returns the same count as Perft, but splits the work between several goroutines each working on its
+// own copy of the position. It can also use bulk counting and a hash table, which give the same results much faster.
+func (p *Position) PerftParallel(depth uint, options PerftOptions) PerftStats {
+	if depth == 0 {
+		return PerftStats{Nodes: 1}
+	}
+
+	_, results := p.PerftDivide(depth, options)
+
+	var total PerftStats
+	for _, stats := range results {
+		total.Add(stats)
+	}
+
+	return total
+}
+
+// PerftDivide is like PerftParallel, but returns the counts for each legal move in the position separately. This is
+// useful for finding which move a bug in the move generator is under by comparing against another engine.
+func (p *Position) PerftDivide(depth uint, options PerftOptions) ([]Move, []PerftStats) {
+	if depth == 0 {
+		return nil, nil
+	}
+
+	moves := p.MovesLegal().AsSlice()
+	results := make([]PerftStats, len(moves))
+
+	threads := options.Threads
+	if threads < 1 {
+		threads = 1
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+
+	for i := 0; i < threads && i < len(moves); i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			// Each goroutine has its own copy of the position since making moves changes it.
+			pos := p.Copy()
+
+			for i := range next {
+				pos.MakeMove(moves[i])
+
+				switch {
+				case options.Extended && depth == 1:
+					results[i] = pos.perftLeaf(moves[i])
+				case options.Extended:
+					results[i] = pos.PerftExtended(depth - 1)
+				default:
+					results[i] = PerftStats{Nodes: pos.perftFast(depth-1, options.Bulk, options.Hash)}
+				}
+
+				pos.UndoMove(moves[i])
+			}
+		}()
+	}
+
+	for i := range moves {
+		next <- i
+	}
+
+	close(next)
+	wg.Wait()
+
+	return moves, results
+}
+
+// perftFast is Perft with optional bulk counting and hashing.
+func (p *Position) perftFast(depth uint, bulk bool, hash *PerftHash) uint {
+	if depth == 0 {
+		return 1
+	}
+
+	// At the last ply every legal move leads to exactly one game, so there's no need to make them.
+	if bulk && depth == 1 {
+		return uint(p.MovesLegal().Len())
+	}
+
+	if hash != nil {
+		if nodes, ok := hash.probe(p.hash, depth); ok {
+			return nodes
+		}
+	}
+
+	nodes := uint(0)
+
+	for _, move := range p.MovesLegal().AsSlice() {
+		p.MakeMove(move)
+		nodes += p.perftFast(depth-1, bulk, hash)
+		p.UndoMove(move)
+	}
+
+	if hash != nil {
+		hash.store(p.hash, depth, nodes)
+	}
+
+	return nodes
+}
+
+// perftEntry is a single entry in the perft hash table. To allow the table to be shared between goroutines without
+// locking, the check field holds the hash of the position XORed with the data. A torn write, where another goroutine
+// writes one field of the entry but not the other, then just looks like an entry for a different position.
+type perftEntry struct {
+	check uint64
+	data  uint64 // data holds the node count in the upper 56 bits and the depth in the lower 8.
+}
+
+// PerftHash is a hash table that stores the number of games from positions that have already been counted, since the
+// same positions are reached through many different move orders. It can be shared between goroutines.
+type PerftHash struct {
+	entries []perftEntry
+	mask    uint64
+}
+
+// NewPerftHash returns a new perft hash table that uses at most the given number of megabytes.
+func NewPerftHash(megabytes int) *PerftHash {
+	// The number of entries is rounded down to a power of two so that the index can be found with a mask rather than a
+	// modulo.
+	count := uint64(megabytes) * 1024 * 1024 / uint64(unsafe.Sizeof(perftEntry{}))
+	size := uint64(1)
+
+	for size*2 <= count {
+		size *= 2
+	}
+
+	return &PerftHash{
+		entries: make([]perftEntry, size),
+		mask:    size - 1,
+	}
+}
+
+func (h *PerftHash) probe(hash uint64, depth uint) (uint, bool) {
+	entry := &h.entries[hash&h.mask]
+
+	data := atomic.LoadUint64(&entry.data)
+	check := atomic.LoadUint64(&entry.check)
+
+	if check^data != hash || uint(data&0xff) != depth {
+		return 0, false
+	}
+
+	return uint(data >> 8), true
+}
+
+func (h *PerftHash) store(hash uint64, depth uint, nodes uint) {
+	entry := &h.entries[hash&h.mask]
+	data := uint64(nodes)<<8 | uint64(depth&0xff)
+
+	atomic.StoreUint64(&entry.data, data)
+	atomic.StoreUint64(&entry.check, hash^data)
+}
@@ -0,0 +1,61 @@
+package position
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPerftExtended tests that the breakdown of perft results by the kind of move made last agrees with other engines.
+// The expected results come from the Chess Programming Wiki: https://www.chessprogramming.org/Perft_Results
+func TestPerftExtended(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		depth    uint
+		expected PerftStats
+	}{
+		{"starting position", StartingPosition, 4, PerftStats{197281, 1576, 0, 0, 0, 469, 8}},
+		{"kiwipete", "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", 3, PerftStats{97862, 17102, 45, 3162, 0, 993, 1}},
+		{"position-3", "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", 4, PerftStats{43238, 3348, 123, 0, 0, 1680, 17}},
+		{"position-4", "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", 3, PerftStats{9467, 1021, 4, 0, 120, 38, 22}},
+	}
+
+	for _, test := range tests {
+		pos, err := NewPositionFromFEN(test.fen)
+		assert.NoError(t, err)
+
+		assert.Equal(t, test.expected, pos.PerftExtended(test.depth), test.name)
+	}
+}
+
+// TestPerftParallel tests that splitting perft between goroutines, bulk counting and hashing all give the same results
+// as Perft.
+func TestPerftParallel(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		depth    uint
+		expected uint
+	}{
+		{"starting position", StartingPosition, 4, 197281},
+		{"kiwipete", "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", 4, 4085603},
+		{"position-3", "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", 5, 674624},
+		{"position-5", "rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8", 4, 2103487},
+	}
+
+	for _, test := range tests {
+		for _, options := range []PerftOptions{
+			{Threads: 1},
+			{Threads: 4, Bulk: true},
+			{Threads: 4, Bulk: true, Hash: NewPerftHash(1)},
+			{Threads: 4, Hash: NewPerftHash(1)},
+		} {
+			pos, err := NewPositionFromFEN(test.fen)
+			assert.NoError(t, err)
+
+			stats := pos.PerftParallel(test.depth, options)
+			assert.Equal(t, test.expected, stats.Nodes, "%s with options %+v", test.name, options)
+		}
+	}
+}
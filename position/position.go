@@ -17,11 +17,28 @@ type Position struct {
 	EnPassant    uint8    // EnPassant holds the position in the Squares array for the en passant target square (i.e. the square that a pawn passed over while moving two squares. It is equal to 255 when there is no target.
 
 	Castling CastlingAvailability // Castling holds castling availability for each side.
+	Chess960 bool                 // Chess960 is true if castling is written as the king capturing its own rook, as in Chess960.
 
 	SideToMove Color
 
 	HalfmoveClock uint // HalfmoveClock stores the number of halfmoves since the last capture or pawn advance.
 	FullMoves     uint // FullMoves stores the number of full moves.
+
+	castlingRooks [4]uint8 // castlingRooks holds the starting square of the rook for each type of castling, indexed by castlingIndex.
+
+	hash    uint64      // hash stores the Zobrist hash of the position, which is updated incrementally as moves are made.
+	history []undoState // history stores information about every earlier position in the game, oldest first.
+}
+
+// undoState stores everything needed to restore the position from before a move exactly when it is undone.
+type undoState struct {
+	move          Move                 // move is the move that was made, which is undone by Unmake.
+	captured      ColoredPiece         // captured is the piece taken by the move, including pawns taken en passant.
+	castling      CastlingAvailability // castling is the castling availability before the move.
+	enPassant     uint8                // enPassant is the en passant target before the move.
+	hash          uint64               // hash is the hash of the position before the move, which is used to detect repetitions.
+	halfmoveClock uint                 // halfmoveClock is the halfmove clock before the move.
+	fullMoves     uint                 // fullMoves is the number of full moves before the move.
 }
 
 const StartingPosition string = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
@@ -147,8 +164,10 @@ func NewPositionFromFEN(input string) (*Position, error) {
 	var enPassantTarget uint8
 	if rawEnPassantTarget == "-" {
 		enPassantTarget = NoEnPassant
+	} else if square, ok := stringSquareMap[rawEnPassantTarget]; ok {
+		enPassantTarget = square
 	} else {
-		enPassantTarget = StringToSquare(rawEnPassantTarget)
+		return nil, fmt.Errorf("invalid en passant target %q in fen string %q", rawEnPassantTarget, input)
 	}
 
 	var sideToMove Color
@@ -175,17 +194,34 @@ func NewPositionFromFEN(input string) (*Position, error) {
 		return nil, fmt.Errorf("invalid FEN string %v, castling rights are omitted", input)
 	}
 
-	return &Position{
+	castling, castlingRooks, err := parseCastling(castlingRights, squares, kingLocation)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fen string %q: %w", input, err)
+	}
+
+	pos := &Position{
 		Squares:       squares,
 		Occupied:      occupied,
 		Pieces:        pieces,
 		KingLocation:  kingLocation,
 		EnPassant:     enPassantTarget,
-		Castling:      castlingAvailabilityFromString(castlingRights),
+		Castling:      castling,
 		SideToMove:    sideToMove,
 		HalfmoveClock: uint(halfmoveClock),
 		FullMoves:     uint(fullMoves),
-	}, nil
+		castlingRooks: castlingRooks,
+	}
+
+	if err := pos.Validate(); err != nil {
+		return nil, fmt.Errorf("illegal position in fen string %q: %w", input, err)
+	}
+
+	// Positions where castling can't be written as the king moving two squares must be from a game of Chess960.
+	pos.Chess960 = pos.needsChess960()
+
+	pos.hash = pos.ComputeHash()
+
+	return pos, nil
 }
 
 // StringFEN returns the current position's FEN string.
@@ -193,7 +229,20 @@ func NewPositionFromFEN(input string) (*Position, error) {
 //   rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1
 // In general:
 // 	 <rank 1>/<rank 2>/<rank 3>/<rank 4>/<rank 5>/<rank 6>/<rank 7>/<rank 8> <side to move> <castling rights> <en passant target> <halfmove clock> <full moves>
+//
+// In Chess960, castling rights are written in X-FEN, which is the same as standard FEN except that the file of the rook
+// is given instead of "K" or "Q" if there is another rook further out on the same side.
 func (b *Position) StringFEN() string {
+	return b.stringFEN(false)
+}
+
+// StringShredderFEN returns the current position's FEN string with the castling rights written in Shredder-FEN, which
+// gives the file of each castling rook, e.g. "HAha" rather than "KQkq".
+func (b *Position) StringShredderFEN() string {
+	return b.stringFEN(true)
+}
+
+func (b *Position) stringFEN(shredder bool) string {
 	var out bytes.Buffer
 
 	// Ranks
@@ -236,7 +285,7 @@ func (b *Position) StringFEN() string {
 	out.WriteString(" ")
 
 	// Castling rights
-	out.WriteString(b.Castling.String())
+	out.WriteString(b.castlingString(shredder))
 	out.WriteString(" ")
 
 	// En passant target
@@ -306,13 +355,9 @@ func (p *Position) PrettyPrint() string {
 // MakeMove makes a move on the chess board, or returns an error if it is invalid.
 // Bitboards for occupation and piece locations are updated through the setSquare function.
 func (p *Position) MakeMove(m Move) bool {
-	// Need to handle 6 special cases:
-	// - White king moving
-	// - Black king moving
-	// (These two cases can move two pieces at once and disable castling)
-
-	// - White rook moving
-	// - Black rook moving
+	// Need to handle 4 special cases:
+	// - A king moving
+	// - A rook moving from or being captured on its starting square
 	// (These two cases mean some castling privileges may be lost)
 
 	// - White pawn moving forward two squares onto an empty square
@@ -320,69 +365,44 @@ func (p *Position) MakeMove(m Move) bool {
 	// (These two cases either perform an en passant capture or set the en passant target square)
 
 	movingPiece := p.Squares[m.From()]
+	priorCastling := p.Castling
 	var newEnPassantTarget uint8 = NoEnPassant
 
+	captured := p.Squares[m.To()]
+
 	switch {
-	case movingPiece == WhiteKing:
-		// Disable any type of castling for the white king as they have moved.
-		p.Castling.off(longW | shortW)
+	case p.isCastle(m, movingPiece):
+		// Moves parsed without a position don't know they are castling, but Unmake needs to. In Chess960 the king moves
+		// onto its own rook, which isn't a capture.
+		m |= maskIsCastle
+		captured = Empty
+	case movingPiece.Colorless() == Pawn && m.To() == p.EnPassant:
+		captured = Pawn.OfColor(p.SideToMove.Invert())
+	}
 
-		// The king has moved two squares and so it is known they have castled.
-		// Here we only need to worry about moving the rook, as the king is moved by the general code outside of the switch.
-		if abs(int(m.From())-int(m.To())) == 2 {
-			// Determine if they have castled long or short.
-			if m.To() == SquareG1 { // Short castle
-				p.setSquare(SquareF1, WhiteRook)
-				p.setSquare(SquareH1, Empty)
-			} else { // Long castle
-				p.setSquare(SquareD1, WhiteRook)
-				p.setSquare(SquareA1, Empty)
-			}
-		}
-	case movingPiece == BlackKing:
-		// Disable any type of castling for the black king as they have moved.
+	// Record the position before the move so that repetitions can be detected later and the move can be undone.
+	p.history = append(p.history, undoState{
+		move:          m,
+		captured:      captured,
+		castling:      p.Castling,
+		enPassant:     p.EnPassant,
+		hash:          p.hash,
+		halfmoveClock: p.HalfmoveClock,
+		fullMoves:     p.FullMoves,
+	})
+
+	// Disable any type of castling for a king that has moved, and castling on the side of a rook that has moved or
+	// been taken.
+	switch movingPiece {
+	case WhiteKing:
+		p.Castling.off(longW | shortW)
+	case BlackKing:
 		p.Castling.off(longB | shortB)
+	}
 
-		// The king has moved two squares and so it is known they have castled.
-		// Here we only need to worry about moving the rook, as the king is moved by the general code outside of the switch.
-		if abs(int(m.From())-int(m.To())) == 2 {
-			// Determine if they have castled long or short.
-			if m.To() == SquareG8 { // Short castle
-				p.setSquare(SquareF8, BlackRook)
-				p.setSquare(SquareH8, Empty)
-			} else { // Long castle
-				p.setSquare(SquareD8, BlackRook)
-				p.setSquare(SquareA8, Empty)
-			}
-		}
-	case movingPiece == WhiteRook:
-		// Disable castling for the king on the side where the rook has moved.
-		if m.From() == SquareA1 {
-			p.Castling.off(longW)
-		} else if m.From() == SquareH1 {
-			p.Castling.off(shortW)
-		}
-	case movingPiece == BlackRook:
-		// Disable castling for the king on the side where the rook has moved.
-		if m.From() == SquareA8 {
-			p.Castling.off(longB)
-		} else if m.From() == SquareH8 {
-			p.Castling.off(shortB)
-		}
-	case p.Squares[m.To()] == WhiteRook:
-		// Disable castling when a white rook is taken.
-		if m.To() == SquareA1 {
-			p.Castling.off(longW)
-		} else if m.To() == SquareH1 {
-			p.Castling.off(shortW)
-		}
-	case p.Squares[m.To()] == BlackRook:
-		// Disable castling when a black rook is taken.
-		if m.To() == SquareA8 {
-			p.Castling.off(longB)
-		} else if m.To() == SquareH8 {
-			p.Castling.off(shortB)
-		}
+	p.Castling.off(p.castlingRightsOn(m.From()) | p.castlingRightsOn(m.To()))
+
+	switch {
 	case movingPiece == WhitePawn && p.Squares[m.To()] == Empty:
 		if m.To()-m.From() == 16 {
 			// The pawn has moved two full squares onto an empty square.
@@ -403,19 +423,28 @@ func (p *Position) MakeMove(m Move) bool {
 		}
 	}
 
-	p.EnPassant = newEnPassantTarget
-	p.setSquare(m.From(), Empty)
+	// The castling availability is modified directly above, so the hash is updated all at once here.
+	p.hash ^= zobristCastling[priorCastling] ^ zobristCastling[p.Castling]
+
+	p.setEnPassant(newEnPassantTarget)
 
-	if m.Promotion() == None {
-		p.setSquare(m.To(), movingPiece)
+	if m.IsCastle() {
+		p.castle(m, p.SideToMove)
 	} else {
-		p.setSquare(m.To(), m.Promotion().OfColor(p.SideToMove))
+		p.setSquare(m.From(), Empty)
+
+		if m.Promotion() == None {
+			p.setSquare(m.To(), movingPiece)
+		} else {
+			p.setSquare(m.To(), m.Promotion().OfColor(p.SideToMove))
+		}
 	}
 
 	p.SideToMove = p.SideToMove.Invert()
+	p.hash ^= zobristSideToMove
 
 	if p.KingInCheck(p.SideToMove.Invert()) {
-		p.UndoMove(m)
+		p.Unmake()
 		return false
 	}
 
@@ -424,70 +453,133 @@ func (p *Position) MakeMove(m Move) bool {
 		p.FullMoves += 1
 	}
 
-	// If there has been no capture and it's not a pawn move, then we need to increment the halfmove clock.
-	if movingPiece != WhitePawn && movingPiece != BlackPawn && p.Squares[m.To()] != Empty {
-		p.HalfmoveClock += 1
-	} else {
+	// The halfmove clock is reset by captures and pawn moves, and otherwise counts up.
+	if movingPiece.Colorless() == Pawn || captured != Empty {
 		p.HalfmoveClock = 0
+	} else {
+		p.HalfmoveClock += 1
 	}
 
 	return true
 }
 
-// UndoMove undoes the last move.
+// UndoMove undoes the last move made, which should be m. Everything is restored from the state recorded when the move
+// was made, so this is the same as Unmake.
 func (p *Position) UndoMove(m Move) {
-	p.EnPassant = m.PriorEnPassantTarget()
-	p.Castling = m.PriorCastling()
-
-	p.setSquare(m.To(), m.Captured())
-	p.setSquare(m.From(), m.Moved())
-
-	if m.Moved().Colorless() == Pawn {
-		if m.To() == p.EnPassant {
-			// This was an en passant move, so we need to undo setting m.To to the captured piece above and instead place
-			// the pawns properly.
-			p.setSquare(m.To(), Empty)
-
-			switch int(m.To()) - int(m.From()) {
-			case 7, 9:
-				p.setSquare(m.To()-8, BlackPawn)
-			case -7, -9:
-				p.setSquare(m.To()+8, WhitePawn)
-			}
+	p.Unmake()
+}
+
+// MakeNullMove passes the turn to the other side without moving a piece, which isn't a legal move but is used by the
+// search to see how good a position is even if the side to move does nothing. It is undone by Unmake. The side to move
+// shouldn't be in check, since the other side could then take the king.
+//
+// Positions before the null move aren't counted as repetitions of positions after it, since the null move resets the
+// halfmove clock.
+func (p *Position) MakeNullMove() {
+	p.history = append(p.history, undoState{
+		move:          NoMove,
+		captured:      Empty,
+		castling:      p.Castling,
+		enPassant:     p.EnPassant,
+		hash:          p.hash,
+		halfmoveClock: p.HalfmoveClock,
+		fullMoves:     p.FullMoves,
+	})
+
+	p.setEnPassant(NoEnPassant)
+
+	p.SideToMove = p.SideToMove.Invert()
+	p.hash ^= zobristSideToMove
+
+	if p.SideToMove == White {
+		p.FullMoves += 1
+	}
+
+	p.HalfmoveClock = 0
+}
+
+// Unmake undoes the last move made, restoring the position to exactly how it was before. It does nothing if no moves
+// have been made.
+func (p *Position) Unmake() {
+	if len(p.history) == 0 {
+		return
+	}
+
+	state := p.history[len(p.history)-1]
+	p.history = p.history[:len(p.history)-1]
+
+	m := state.move
+	side := p.SideToMove.Invert()
+
+	// A null move didn't move any pieces, so there is nothing to put back.
+	switch {
+	case m == NoMove:
+	case m.IsCastle():
+		p.uncastle(m, side)
+	default:
+		moved := p.Squares[m.To()]
+		if m.IsPromotion() {
+			moved = Pawn.OfColor(side)
 		}
-	} else if m.Moved().Colorless() == King {
-		sideMoving := m.Moved().Color()
-
-		switch int(m.To()) - int(m.From()) {
-		case 2: // Short castling for white, long castling for black
-			if sideMoving == White {
-				p.setSquare(SquareH1, WhiteRook)
-				p.setSquare(SquareF1, Empty)
-			} else if sideMoving == Black {
-				p.setSquare(SquareH8, BlackRook)
-				p.setSquare(SquareF8, Empty)
-			}
 
-		case -2: // Long castling for white, short castling for black
-			if sideMoving == White {
-				p.setSquare(SquareA1, WhiteRook)
-				p.setSquare(SquareD1, Empty)
-			} else if sideMoving == Black {
-				p.setSquare(SquareA8, BlackRook)
-				p.setSquare(SquareD8, Empty)
+		p.setSquare(m.From(), moved)
+		p.setSquare(m.To(), Empty)
+
+		// Pawns taken en passant are behind the square the capturing pawn moved to, rather than on it.
+		capturedSquare := m.To()
+		if moved.Colorless() == Pawn && m.To() == state.enPassant {
+			if side == White {
+				capturedSquare -= 8
+			} else {
+				capturedSquare += 8
 			}
 		}
+
+		p.setSquare(capturedSquare, state.captured)
 	}
 
-	p.SideToMove = p.SideToMove.Invert()
+	p.SideToMove = side
+	p.Castling = state.castling
+	p.EnPassant = state.enPassant
+	p.HalfmoveClock = state.halfmoveClock
+	p.FullMoves = state.fullMoves
+
+	// Setting the squares above changes the hash, but it is simpler to restore it than to undo every other change to it.
+	p.hash = state.hash
+}
+
+// ParseMove parses a UCI-style long algebraic notation move and finds the matching legal move in the position. Unlike
+// the ParseMove function, the returned move contains the moved and captured pieces and the prior castling and en
+// passant state, so it can be safely made and undone.
+func (p *Position) ParseMove(str string) (Move, error) {
+	parsed, err := ParseMove(str)
+	if err != nil {
+		return NoMove, err
+	}
 
-	// If the side to move is now black, we need to subtract one from the fullmove clock.
-	if p.SideToMove == Black {
-		p.FullMoves -= 1
+	for _, move := range p.MovesLegal().AsSlice() {
+		if move.From() == parsed.From() && move.To() == parsed.To() && move.Promotion() == parsed.Promotion() {
+			return move, nil
+		}
 	}
 
-	// TODO: update proper recovery of the fullmove and halfmove clock
+	if p.Squares[parsed.From()] == Empty {
+		return NoMove, fmt.Errorf("illegal move %q, there is no piece on %s in position %s", str, SquareToString(parsed.From()), p.StringFEN())
+	}
+
+	if p.Squares[parsed.From()].Color() != p.SideToMove {
+		return NoMove, fmt.Errorf("illegal move %q, it is %s's turn to move in position %s", str, p.SideToMove, p.StringFEN())
+	}
 
+	return NoMove, fmt.Errorf("illegal move %q in position %s", str, p.StringFEN())
+}
+
+// Copy returns a copy of the position that can be changed without affecting the original.
+func (p *Position) Copy() *Position {
+	copied := *p
+	copied.history = append([]undoState{}, p.history...)
+
+	return &copied
 }
 
 // KingInCheck returns true if the given side to move has their king in check.
@@ -569,21 +661,19 @@ func (p *Position) IsAttacked(square uint8, color Color) bool {
 		return true
 	}
 
+	occupied := p.Occupied[color.Invert()] | p.Occupied[color]
+
 	// Rook/queen attacks
-	rookBlockers := (p.Occupied[color.Invert()] | p.Occupied[color]) & rookMasks[square]
-	rookKey := (uint64(rookBlockers) * rookMagics[square].multiplier) >> uint64(rookMagics[square].shift)
-	if (rookMoves[square][rookKey] & p.Occupied[color] & (p.Pieces[Rook] | p.Pieces[Queen])) != 0 {
+	if (rookAttacks(square, occupied) & p.Occupied[color] & (p.Pieces[Rook] | p.Pieces[Queen])) != 0 {
 		return true
 	}
 
 	// Bishop/queen attacks
-	bishopBlockers := (p.Occupied[color.Invert()] | p.Occupied[color]) & bishopMasks[square]
-	bishopKey := (uint64(bishopBlockers) * bishopMagics[square].multiplier) >> uint64(bishopMagics[square].shift)
-	return (bishopMoves[square][bishopKey] & p.Occupied[color] & (p.Pieces[Bishop] | p.Pieces[Queen])) != 0
+	return (bishopAttacks(square, occupied) & p.Occupied[color] & (p.Pieces[Bishop] | p.Pieces[Queen])) != 0
 }
 
 // setSquare sets a specific square on the board to a empty or to a certain piece.
-// This updates the bitboards as well as modifying Squares.
+// This updates the bitboards and the hash as well as modifying Squares.
 func (p *Position) setSquare(square uint8, newPiece ColoredPiece) {
 	oldPiece := p.Squares[square]
 
@@ -596,11 +686,13 @@ func (p *Position) setSquare(square uint8, newPiece ColoredPiece) {
 	if oldPiece != Empty {
 		p.Occupied[oldPiece.Color()].Off(square)
 		p.Pieces[oldPiece.Colorless()].Off(square)
+		p.hash ^= zobristPieces[oldPiece][square]
 	}
 
 	if newPiece != Empty {
 		p.Occupied[newPiece.Color()].On(square)
 		p.Pieces[newPiece.Colorless()].On(square)
+		p.hash ^= zobristPieces[newPiece][square]
 	}
 
 	if newPiece == WhiteKing || newPiece == BlackKing {
@@ -1,6 +1,7 @@
 package position
 
 import (
+	"math/rand"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -76,6 +77,7 @@ func TestInvalidFen(t *testing.T) {
 		{"r3k2rp1pp1pb1/8/bn2Qnp1/2qPN3/1p2P3/2N5/PPPBBPPP/R3K2R b KQkq - 3 2", "One rank is too long"},
 		{"2kr3r//bn2Qnp1/3PN3/1p2P3/2N5/PPPBBPPP/R3K2R b KQ - 3 2", "Rank is empty"},
 		{"5k2/8/8/8/8/8/8/4K2R w  - 0 1", "Castling rights are omitted"},
+		{"5k2/8/8/8/8/8/8/4K2R w K z9 0 1", "En passant target isn't a square"},
 	}
 
 	for _, test := range tests {
@@ -84,6 +86,43 @@ func TestInvalidFen(t *testing.T) {
 	}
 }
 
+// TestIllegalFEN makes sure that FEN strings for positions that can't happen in a game are rejected with the right
+// kind of error.
+func TestIllegalFEN(t *testing.T) {
+	tests := []struct {
+		fen  string
+		want error
+		why  string
+	}{
+		{"8/8/8/8/8/8/8/4K3 w - - 0 1", ErrKingCount, "Black has no king"},
+		{"4k3/8/8/8/8/8/8/3KK3 w - - 0 1", ErrKingCount, "White has two kings"},
+		{"4k3/pppppppp/p7/8/8/8/8/4K3 w - - 0 1", ErrPieceCount, "Black has nine pawns"},
+		{"qqqqkqqq/qqqqqqqq/q7/8/8/8/8/4K3 w - - 0 1", ErrPieceCount, "Black has too many pieces"},
+		{"4k3/8/8/8/8/8/8/P3K3 w - - 0 1", ErrPawnOnBackRank, "Pawn on the first rank"},
+		{"4k2p/8/8/8/8/8/8/4K3 w - - 0 1", ErrPawnOnBackRank, "Pawn on the last rank"},
+		{"4k3/8/8/8/8/8/8/4K2r b - - 0 1", ErrOpponentInCheck, "Black is to move but White is in check"},
+		{"4k3/8/8/8/8/8/8/4K3 w K - 0 1", ErrCastlingRights, "White can castle short with no rook"},
+		{"4k3/8/8/8/8/8/8/4K2R w B - 0 1", ErrCastlingRights, "White can castle with a rook on b1 that isn't there"},
+		{"r3k3/8/8/8/8/8/8/4K3 w q - 0 1", nil, "Black can castle long"},
+		{"r7/4k3/8/8/8/8/8/4K3 w q - 0 1", ErrCastlingRights, "Black can castle with the king off the back rank"},
+		{"4k3/8/8/8/8/8/8/4K3 w - e6 0 1", ErrEnPassant, "No pawn in front of the en passant square"},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", nil, "Black has just played d5"},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d3 0 1", ErrEnPassant, "En passant square on the wrong rank"},
+		{"4k3/3p4/8/3pP3/8/8/8/4K3 w - d6 0 1", ErrEnPassant, "The pawn couldn't have come from d7"},
+		{"4k3/8/8/8/3pP3/8/8/4K3 b - e3 0 1", nil, "White has just played e4"},
+	}
+
+	for _, test := range tests {
+		_, err := NewPositionFromFEN(test.fen)
+
+		if test.want == nil {
+			assert.NoError(t, err, test.why)
+		} else {
+			assert.ErrorIs(t, err, test.want, test.why)
+		}
+	}
+}
+
 // TestValidMoves tests that performing valid moves on the position gives the expected FEN string.
 func TestValidMoves(t *testing.T) {
 	tests := []struct {
@@ -199,4 +238,144 @@ func TestValidMoves(t *testing.T) {
 	}
 }
 
-// TODO: write tests for generating moves and then unmaking them
+// TestMakeUnmakeRandomGames plays random games, checking that unmaking each move restores the position exactly, and
+// then that unmaking the whole game gets back to the start.
+func TestMakeUnmakeRandomGames(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	starts := []string{
+		StartingPosition,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9",
+	}
+
+	for _, start := range starts {
+		for game := 0; game < 20; game++ {
+			pos, err := NewPositionFromFEN(start)
+			assert.NoError(t, err)
+
+			startFEN := pos.StringFEN()
+
+			plies := 0
+
+			for ; plies < 200; plies++ {
+				moves := pos.MovesLegal().AsSlice()
+				if len(moves) == 0 {
+					break
+				}
+
+				move := moves[rng.Intn(len(moves))]
+				fen, hash := pos.StringFEN(), pos.Hash()
+
+				assert.True(t, pos.MakeMove(move))
+				pos.Unmake()
+
+				if !assert.Equal(t, fen, pos.StringFEN(), "unmaking %s", move) || !assert.Equal(t, hash, pos.Hash(), "unmaking %s in %s", move, fen) {
+					return
+				}
+
+				pos.MakeMove(move)
+			}
+
+			for ; plies > 0; plies-- {
+				pos.Unmake()
+			}
+
+			assert.Equal(t, startFEN, pos.StringFEN(), "unmaking a whole game from %s", start)
+		}
+	}
+}
+
+// TestRepetitionCount tests that shuffling pieces back and forth is detected as a repetition, and that the count is
+// reset by an irreversible move.
+func TestRepetitionCount(t *testing.T) {
+	position, err := NewPositionFromFEN(StartingPosition)
+	assert.NoError(t, err)
+
+	shuffle := []string{"g1f3", "g8f6", "f3g1", "f6g8"}
+
+	assert.Equal(t, 1, position.RepetitionCount())
+	assert.False(t, position.IsRepetition())
+
+	for i := 2; i <= 3; i++ {
+		for _, move := range shuffle {
+			parsed, err := ParseMove(move)
+			assert.NoError(t, err)
+
+			position.MakeMove(parsed)
+		}
+
+		assert.Equal(t, i, position.RepetitionCount(), "expected position to have occurred %d times", i)
+		assert.True(t, position.IsRepetition())
+	}
+
+	parsed, err := ParseMove("e2e4")
+	assert.NoError(t, err)
+	position.MakeMove(parsed)
+
+	assert.Equal(t, 1, position.RepetitionCount(), "expected pawn move to reset repetitions")
+}
+
+// TestFiftyMoveDraw tests that the fifty-move rule is detected from the halfmove clock.
+func TestFiftyMoveDraw(t *testing.T) {
+	notDrawn, err := NewPositionFromFEN("4k3/8/8/8/8/8/8/R3K3 w - - 99 80")
+	assert.NoError(t, err)
+	assert.False(t, notDrawn.IsFiftyMoveDraw())
+
+	drawn, err := NewPositionFromFEN("4k3/8/8/8/8/8/8/R3K3 w - - 100 80")
+	assert.NoError(t, err)
+	assert.True(t, drawn.IsFiftyMoveDraw())
+}
+
+// TestUndoMoveHalfmoveClock tests that generating legal moves, which makes and undoes every move, leaves the halfmove
+// clock unchanged.
+func TestUndoMoveHalfmoveClock(t *testing.T) {
+	fen := "r3k2r/pbppqppp/np3n2/2b1p3/2B1P3/NP3N2/PBPPQPPP/R3K2R w KQkq - 4 8"
+
+	position, err := NewPositionFromFEN(fen)
+	assert.NoError(t, err)
+
+	position.MovesLegal()
+	assert.Equal(t, fen, position.StringFEN(), "expected generating legal moves not to change the position")
+}
+
+// TestHalfmoveClock tests that the halfmove clock is reset by captures and pawn moves, and that the full move counter
+// isn't changed by trying an illegal move.
+func TestHalfmoveClock(t *testing.T) {
+	pos, err := NewPositionFromFEN("4k3/8/8/8/8/8/8/n2RK3 b - - 7 30")
+	assert.NoError(t, err)
+
+	illegal, err := ParseMove("e8d8")
+	assert.NoError(t, err)
+	assert.False(t, pos.MakeMove(NewMove(illegal.From(), illegal.To(), BlackKing, Empty, None, pos.Castling, pos.EnPassant)))
+	assert.Equal(t, "4k3/8/8/8/8/8/8/n2RK3 b - - 7 30", pos.StringFEN())
+
+	for _, str := range []string{"e8e7", "d1a1", "e7e6", "a1a2"} {
+		move, err := pos.ParseMove(str)
+		assert.NoError(t, err)
+		assert.True(t, pos.MakeMove(move))
+	}
+
+	assert.Equal(t, "8/8/4k3/8/8/8/R7/4K3 b - - 2 32", pos.StringFEN())
+}
+
+// TestNullMove tests that a null move only changes the side to move and en passant target, and is undone exactly.
+func TestNullMove(t *testing.T) {
+	fen := "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2"
+
+	pos, err := NewPositionFromFEN(fen)
+	assert.NoError(t, err)
+
+	hash := pos.Hash()
+
+	pos.MakeNullMove()
+	assert.Equal(t, "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 2", pos.StringFEN())
+	assert.Equal(t, pos.ComputeHash(), pos.Hash())
+	assert.False(t, pos.IsRepetition())
+
+	pos.Unmake()
+	assert.Equal(t, fen, pos.StringFEN())
+	assert.Equal(t, hash, pos.Hash())
+}
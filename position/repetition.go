@@ -0,0 +1,29 @@
+package position
+
+// RepetitionCount returns the number of times the current position has occurred in the game, including the current
+// occurrence. Positions are compared using their hashes, and only positions reached since the game started or was loaded
+// from a FEN string are known about.
+func (p *Position) RepetitionCount() int {
+	count := 1
+
+	// A position can only repeat with the same side to move, so only every other earlier position needs to be checked.
+	// Captures and pawn moves can't be undone, so there's also no need to look back further than the halfmove clock.
+	for i := len(p.history) - 2; i >= 0 && i >= len(p.history)-int(p.HalfmoveClock); i -= 2 {
+		if p.history[i].hash == p.hash {
+			count++
+		}
+	}
+
+	return count
+}
+
+// IsRepetition returns true if the current position has occurred at least once before in the game.
+func (p *Position) IsRepetition() bool {
+	return p.RepetitionCount() > 1
+}
+
+// IsFiftyMoveDraw returns true if fifty full moves have been made without a capture or a pawn move, so that either
+// player can claim a draw.
+func (p *Position) IsFiftyMoveDraw() bool {
+	return p.HalfmoveClock >= 100
+}
@@ -0,0 +1,218 @@
+package position
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StringSAN returns the move in Standard Algebraic Notation (SAN), e.g. "Nbd7", "exd8=Q+" or "O-O-O#". The move must be
+// legal in the current position, since the position is needed to work out whether the move needs disambiguating and
+// whether it gives check or checkmate.
+//
+// For more information: https://www.chessprogramming.org/Algebraic_Chess_Notation#Standard_Algebraic_Notation_.28SAN.29
+func (p *Position) StringSAN(m Move) string {
+	var out strings.Builder
+
+	piece := m.Moved().Colorless()
+
+	switch {
+	case m.IsCastle():
+		if m.To() > m.From() {
+			out.WriteString("O-O")
+		} else {
+			out.WriteString("O-O-O")
+		}
+
+	case piece == Pawn:
+		// Pawn captures are always disambiguated using the file the pawn started on, e.g. "exd5".
+		if m.IsCapture() {
+			out.WriteByte(fileChar(m.From()))
+			out.WriteString("x")
+		}
+
+		out.WriteString(SquareToString(m.To()))
+
+		if m.IsPromotion() {
+			out.WriteString("=")
+			out.WriteString(m.Promotion().String())
+		}
+
+	default:
+		out.WriteString(piece.String())
+		out.WriteString(p.disambiguation(m))
+
+		if m.IsCapture() {
+			out.WriteString("x")
+		}
+
+		out.WriteString(SquareToString(m.To()))
+	}
+
+	if p.MakeMove(m) {
+		if p.KingInCheck(p.SideToMove) {
+			if p.MovesLegal().Len() == 0 {
+				out.WriteString("#")
+			} else {
+				out.WriteString("+")
+			}
+		}
+
+		p.UndoMove(m)
+	}
+
+	return out.String()
+}
+
+// disambiguation returns the part of a piece move in SAN that says which of several identical pieces is moving. If no
+// other piece of the same type can move to the same square, it is empty. Otherwise it is the file the piece is moving
+// from if that is enough to tell them apart, then the rank, and otherwise both.
+func (p *Position) disambiguation(m Move) string {
+	var ambiguous, sameFile, sameRank bool
+
+	for _, other := range p.MovesLegal().AsSlice() {
+		if other.Moved() != m.Moved() || other.To() != m.To() || other.From() == m.From() {
+			continue
+		}
+
+		ambiguous = true
+
+		if other.From()%8 == m.From()%8 {
+			sameFile = true
+		}
+
+		if other.From()/8 == m.From()/8 {
+			sameRank = true
+		}
+	}
+
+	switch {
+	case !ambiguous:
+		return ""
+	case !sameFile:
+		return string(fileChar(m.From()))
+	case !sameRank:
+		return string(rankChar(m.From()))
+	default:
+		return SquareToString(m.From())
+	}
+}
+
+// ParseSAN parses a move written in Standard Algebraic Notation and finds the matching legal move in the position.
+// It is tolerant of common variations, so it accepts:
+//   - castling written with zeros, e.g. "0-0" and "0-0-0"
+//   - promotions with or without the equals sign, and with a lowercase piece, e.g. "e8=Q", "e8Q" and "e8q"
+//   - missing or unnecessary capture markers, e.g. "Nd5" when it is a capture or "exd6e.p."
+//   - missing or incorrect check and mate suffixes, and annotations such as "!?"
+//   - over-disambiguated moves, including long algebraic notation such as "Ng1f3" or "e2-e4"
+func (p *Position) ParseSAN(str string) (Move, error) {
+	san := strings.TrimSpace(str)
+	san = strings.TrimSuffix(san, "e.p.")
+	san = strings.TrimRight(san, "+#!? ")
+
+	switch san {
+	case "O-O", "0-0", "o-o":
+		return p.findCastle(str, true)
+	case "O-O-O", "0-0-0", "o-o-o":
+		return p.findCastle(str, false)
+	}
+
+	san = strings.NewReplacer("x", "", ":", "", "-", "", "=", "").Replace(san)
+
+	promotion := None
+
+	if len(san) >= 3 && isRankChar(san[len(san)-2]) {
+		switch last := san[len(san)-1]; last {
+		case 'Q', 'R', 'B', 'N', 'q', 'r', 'b', 'n':
+			promotion = strToPiece(string(last))
+			san = san[:len(san)-1]
+		}
+	}
+
+	if len(san) < 2 {
+		return NoMove, fmt.Errorf("invalid SAN move %q", str)
+	}
+
+	to, ok := stringSquareMap[san[len(san)-2:]]
+	if !ok {
+		return NoMove, fmt.Errorf("invalid SAN move %q, %q is not a square", str, san[len(san)-2:])
+	}
+
+	san = san[:len(san)-2]
+
+	piece := Pawn
+
+	if len(san) > 0 && strings.IndexByte("PNBRQK", san[0]) != -1 {
+		piece = strToPiece(string(san[0]))
+		san = san[1:]
+	}
+
+	// Anything left over says which square the piece is moving from.
+	fromFile, fromRank := -1, -1
+
+	for i := 0; i < len(san); i++ {
+		switch {
+		case isFileChar(san[i]):
+			fromFile = int(san[i] - 'a')
+		case isRankChar(san[i]):
+			fromRank = int(san[i] - '1')
+		default:
+			return NoMove, fmt.Errorf("invalid SAN move %q, can't understand %q", str, san)
+		}
+	}
+
+	var matches []Move
+
+	for _, move := range p.MovesLegal().AsSlice() {
+		if move.Moved().Colorless() != piece || move.To() != to || move.Promotion() != promotion {
+			continue
+		}
+
+		if fromFile != -1 && int(move.From()%8) != fromFile {
+			continue
+		}
+
+		if fromRank != -1 && int(move.From()/8) != fromRank {
+			continue
+		}
+
+		matches = append(matches, move)
+	}
+
+	switch len(matches) {
+	case 0:
+		return NoMove, fmt.Errorf("illegal SAN move %q in position %s", str, p.StringFEN())
+	case 1:
+		return matches[0], nil
+	default:
+		return NoMove, fmt.Errorf("ambiguous SAN move %q in position %s, could be %d different moves", str, p.StringFEN(), len(matches))
+	}
+}
+
+// findCastle finds the legal castling move for the side to move, either short (kingside) or long (queenside).
+func (p *Position) findCastle(str string, short bool) (Move, error) {
+	for _, move := range p.MovesLegal().AsSlice() {
+		if move.IsCastle() && (move.To() > move.From()) == short {
+			return move, nil
+		}
+	}
+
+	return NoMove, fmt.Errorf("illegal SAN move %q in position %s, can't castle", str, p.StringFEN())
+}
+
+// fileChar returns the letter for the file of a square, e.g. SquareE4 -> 'e'.
+func fileChar(square uint8) byte {
+	return 'a' + square%8
+}
+
+// rankChar returns the digit for the rank of a square, e.g. SquareE4 -> '4'.
+func rankChar(square uint8) byte {
+	return '1' + square/8
+}
+
+func isFileChar(c byte) bool {
+	return c >= 'a' && c <= 'h'
+}
+
+func isRankChar(c byte) bool {
+	return c >= '1' && c <= '8'
+}
@@ -0,0 +1,101 @@
+package position
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestStringSAN tests that moves are formatted in SAN with the correct disambiguation and check and mate suffixes.
+func TestStringSAN(t *testing.T) {
+	tests := []struct {
+		fen      string
+		move     string
+		expected string
+	}{
+		{StartingPosition, "e2e4", "e4"},
+		{StartingPosition, "g1f3", "Nf3"},
+		{"1n2k3/8/5n2/8/8/8/8/4K3 b - - 0 1", "b8d7", "Nbd7"},
+		{"1n2k3/8/1n6/8/8/8/8/4K3 b - - 0 1", "b8d7", "N8d7"},
+		{"6k1/8/8/8/8/Q6K/8/Q1Q5 w - - 0 1", "a1b2", "Qa1b2"},
+		{"3r4/4P3/8/8/3k4/8/8/4K3 w - - 0 1", "e7d8q", "exd8=Q+"},
+		{"2rkr3/2p1p3/8/8/8/8/8/R3K3 w Q - 0 1", "e1c1", "O-O-O#"},
+		{"r3k2r/pbppqppp/np3n2/2b1p3/2B1P3/NP3N2/PBPPQPPP/R3K2R w KQkq - 4 8", "e1g1", "O-O"},
+		{"r3k2r/pbppqppp/np3n2/2b1p3/2B1P3/NP3N2/PBPPQPPP/R3K2R w KQkq - 4 8", "c4f7", "Bxf7+"},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6", "exd6"},
+		{"4k3/8/4K3/8/8/8/8/R7 w - - 0 1", "a1a8", "Ra8#"},
+	}
+
+	for _, test := range tests {
+		pos, err := NewPositionFromFEN(test.fen)
+		assert.NoError(t, err)
+
+		move, err := pos.ParseMove(test.move)
+		assert.NoError(t, err)
+
+		assert.Equal(t, test.expected, pos.StringSAN(move), "wrong SAN for %s in %s", test.move, test.fen)
+	}
+}
+
+// TestParseSAN tests that SAN moves, including common variations, are parsed into the correct legal move.
+func TestParseSAN(t *testing.T) {
+	tests := []struct {
+		fen      string
+		san      string
+		expected string
+	}{
+		{StartingPosition, "e4", "e2e4"},
+		{StartingPosition, "Nf3", "g1f3"},
+		{StartingPosition, "Ng1f3", "g1f3"},
+		{StartingPosition, "e2-e4", "e2e4"},
+		{StartingPosition, "e4!?", "e2e4"},
+		{"1n2k3/8/5n2/8/8/8/8/4K3 b - - 0 1", "Nbd7", "b8d7"},
+		{"1n2k3/8/1n6/8/8/8/8/4K3 b - - 0 1", "N6d7", "b6d7"},
+		{"3r4/4P3/8/8/3k4/8/8/4K3 w - - 0 1", "exd8=Q+", "e7d8q"},
+		{"3r4/4P3/8/8/3k4/8/8/4K3 w - - 0 1", "exd8Q", "e7d8q"},
+		{"3r4/4P3/8/8/3k4/8/8/4K3 w - - 0 1", "ed8n", "e7d8n"},
+		{"3r4/4P3/8/8/3k4/8/8/4K3 w - - 0 1", "e8=B", "e7e8b"},
+		{"2rkr3/2p1p3/8/8/8/8/8/R3K3 w Q - 0 1", "O-O-O#", "e1c1"},
+		{"2rkr3/2p1p3/8/8/8/8/8/R3K3 w Q - 0 1", "0-0-0", "e1c1"},
+		{"r3k2r/pbppqppp/np3n2/2b1p3/2B1P3/NP3N2/PBPPQPPP/R3K2R w KQkq - 4 8", "O-O", "e1g1"},
+		{"r3k2r/pbppqppp/np3n2/2b1p3/2B1P3/NP3N2/PBPPQPPP/R3K2R w KQkq - 4 8", "Bf7", "c4f7"},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "exd6e.p.", "e5d6"},
+		{"6k1/8/8/8/8/Q6K/8/Q1Q5 w - - 0 1", "Qa1b2", "a1b2"},
+		{"4k3/8/4K3/8/8/8/8/R7 w - - 0 1", "Ra8", "a1a8"},
+	}
+
+	for _, test := range tests {
+		pos, err := NewPositionFromFEN(test.fen)
+		assert.NoError(t, err)
+
+		move, err := pos.ParseSAN(test.san)
+		if assert.NoError(t, err, "wasn't expecting an error parsing %s in %s", test.san, test.fen) {
+			assert.Equal(t, test.expected, move.String(), "wrong move for %s in %s", test.san, test.fen)
+		}
+	}
+}
+
+// TestParseSANInvalid tests that illegal, ambiguous and malformed SAN moves are rejected.
+func TestParseSANInvalid(t *testing.T) {
+	tests := []struct {
+		fen string
+		san string
+		why string
+	}{
+		{StartingPosition, "e5", "Pawn can't move three squares"},
+		{StartingPosition, "Nd4", "No knight can reach d4"},
+		{StartingPosition, "O-O", "Can't castle through pieces"},
+		{StartingPosition, "Zz9", "Not a move"},
+		{StartingPosition, "", "Empty"},
+		{"1n2k3/8/5n2/8/8/8/8/4K3 b - - 0 1", "Nd7", "Two knights can move to d7"},
+		{"3r4/4P3/8/8/3k4/8/8/4K3 w - - 0 1", "e8", "Promotion piece is missing"},
+	}
+
+	for _, test := range tests {
+		pos, err := NewPositionFromFEN(test.fen)
+		assert.NoError(t, err)
+
+		_, err = pos.ParseSAN(test.san)
+		assert.Error(t, err, "expected an error parsing %s, invalid because %q", test.san, test.why)
+	}
+}
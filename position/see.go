@@ -0,0 +1,187 @@
+package position
+
+// seeValues are the values of pieces in centipawns used by static exchange evaluation. The king is never captured, so
+// its value doesn't matter.
+var seeValues = [...]int16{
+	Pawn:   100,
+	Knight: 300,
+	Bishop: 300,
+	Rook:   500,
+	Queen:  900,
+	King:   0,
+	None:   0,
+}
+
+// SEE returns the material in centipawns the side to move can expect to win by making the move, using static exchange
+// evaluation. This plays out every capture on the square the move goes to, with each side capturing with its least
+// valuable piece and free to stop capturing whenever that would lose material. Sliding pieces behind other attackers
+// join in once the pieces in front of them have captured, but pins and checks are ignored.
+//
+// For a quiet move this says whether the piece moved can be won, and for a capture whether the capture wins or loses
+// material overall, e.g. a queen taking a pawn defended by a pawn is worth -800.
+//
+// For more information: https://www.chessprogramming.org/Static_Exchange_Evaluation
+func (p *Position) SEE(m Move) int16 {
+	if m.IsCastle() {
+		return 0
+	}
+
+	var gain [32]int16
+
+	to := m.To()
+	occupied, attackers, value := p.seeStart(m)
+
+	gain[0] = seeValues[m.Captured().Colorless()]
+	if m.IsPromotion() {
+		gain[0] += seeValues[m.Promotion()] - seeValues[Pawn]
+	}
+
+	side := p.SideToMove.Invert()
+	depth := 0
+
+	for depth < len(gain)-1 {
+		square, piece := p.leastValuableAttacker(attackers & p.Occupied[side])
+		if piece == None {
+			break
+		}
+
+		occupied &^= Bitboard(1) << square
+		attackers = p.seeAttackers(to, occupied, attackers, piece)
+
+		// The king can only capture if the other side can't capture back.
+		if piece == King && attackers&p.Occupied[side.Invert()] != 0 {
+			break
+		}
+
+		// gain[depth] is the score for the side that made the capture at that depth if the piece it captured with is then
+		// taken.
+		depth++
+		gain[depth] = value - gain[depth-1]
+		value = seeValues[piece]
+		side = side.Invert()
+	}
+
+	// Work back through the captures, letting each side stop capturing if that is better than carrying on.
+	for ; depth > 0; depth-- {
+		gain[depth-1] = -max16(-gain[depth-1], gain[depth])
+	}
+
+	return gain[0]
+}
+
+// SEEAtLeast returns true if the static exchange evaluation of a move is at least the threshold, e.g. a threshold of 0
+// asks whether the move doesn't lose material. It gives the same answer as comparing the result of SEE, but can stop
+// as soon as the answer is known, which makes it cheaper when only a yes or no is needed.
+func (p *Position) SEEAtLeast(m Move, threshold int16) bool {
+	if m.IsCastle() {
+		return threshold <= 0
+	}
+
+	to := m.To()
+	occupied, attackers, value := p.seeStart(m)
+
+	// swap is how far above the threshold the side to move is if the exchange stops here, from the point of view of the
+	// side that has just captured.
+	swap := seeValues[m.Captured().Colorless()] - threshold
+	if m.IsPromotion() {
+		swap += seeValues[m.Promotion()] - seeValues[Pawn]
+	}
+
+	if swap < 0 {
+		return false
+	}
+
+	// Even losing the piece that moved leaves the side to move at or above the threshold.
+	swap = value - swap
+	if swap <= 0 {
+		return true
+	}
+
+	side := p.SideToMove
+	result := true
+
+	for {
+		side = side.Invert()
+
+		square, piece := p.leastValuableAttacker(attackers & p.Occupied[side])
+		if piece == None {
+			break
+		}
+
+		occupied &^= Bitboard(1) << square
+		attackers = p.seeAttackers(to, occupied, attackers, piece)
+
+		// The king can only capture if the other side can't capture back, otherwise the exchange ends before it.
+		if piece == King {
+			if attackers&p.Occupied[side.Invert()] != 0 {
+				break
+			}
+
+			return !result
+		}
+
+		result = !result
+
+		swap = seeValues[piece] - swap
+		if swap < 0 || (swap == 0 && result) {
+			break
+		}
+	}
+
+	return result
+}
+
+// seeStart returns the occupied squares and attackers on the square a move goes to once the move has been made, and
+// the value of the piece that is then on the square.
+func (p *Position) seeStart(m Move) (occupied Bitboard, attackers Bitboard, value int16) {
+	from, to := m.From(), m.To()
+	moved := m.Moved().Colorless()
+
+	occupied = (p.Occupied[White] | p.Occupied[Black]) &^ (Bitboard(1) << from)
+
+	// Taking en passant removes a pawn from a different square to the one moved to.
+	if moved == Pawn && m.IsCapture() && p.Squares[to] == Empty {
+		if p.SideToMove == White {
+			occupied &^= Bitboard(1) << (to - 8)
+		} else {
+			occupied &^= Bitboard(1) << (to + 8)
+		}
+	}
+
+	attackers = p.seeAttackers(to, occupied, p.AttackersTo(to, occupied), None)
+
+	value = seeValues[moved]
+	if m.IsPromotion() {
+		value = seeValues[m.Promotion()]
+	}
+
+	return occupied, attackers, value
+}
+
+// seeAttackers updates the attackers of a square after a piece has moved off the occupied squares to capture on it,
+// adding any sliding pieces that were behind it and removing pieces that are no longer on the board.
+func (p *Position) seeAttackers(square uint8, occupied, attackers Bitboard, moved Piece) Bitboard {
+	switch moved {
+	case Pawn, Bishop:
+		attackers |= bishopAttacks(square, occupied) & (p.Pieces[Bishop] | p.Pieces[Queen])
+	case Rook:
+		attackers |= rookAttacks(square, occupied) & (p.Pieces[Rook] | p.Pieces[Queen])
+	case Queen:
+		attackers |= bishopAttacks(square, occupied) & (p.Pieces[Bishop] | p.Pieces[Queen])
+		attackers |= rookAttacks(square, occupied) & (p.Pieces[Rook] | p.Pieces[Queen])
+	}
+
+	return attackers & occupied
+}
+
+// leastValuableAttacker returns the square and type of the least valuable piece out of the attackers, or None if there
+// aren't any.
+func (p *Position) leastValuableAttacker(attackers Bitboard) (uint8, Piece) {
+	for piece := Pawn; piece <= King; piece++ {
+		if pieces := attackers & p.Pieces[piece]; pieces != 0 {
+			return pieces.FirstOn(), piece
+		}
+	}
+
+	return 0, None
+}
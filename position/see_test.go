@@ -0,0 +1,73 @@
+package position
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSEE tests static exchange evaluation on positions from the Chess Programming Wiki and on exchanges involving
+// x-rays, en passant, promotions and the king.
+func TestSEE(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		move     string
+		expected int16
+	}{
+		{"undefended pawn", "1k1r4/1pp4p/p7/4p3/8/P5P1/1PP4P/2K1R3 w - - 0 1", "e1e5", 100},
+		{"knight for pawn", "1k1r3q/1ppn3p/p4b2/4p3/8/P2N2P1/1PP1R1BP/2K1Q3 w - - 0 1", "d3e5", -200},
+		{"queen for defended pawn", "4k3/8/3p4/4p3/8/8/8/4QK2 w - - 0 1", "e1e5", -800},
+		{"x-ray through the rook", "3rk3/8/8/3p4/8/8/3R4/3QK3 w - - 0 1", "d2d5", 100},
+		{"pawn takes knight", "4k3/8/5p2/4n3/3P4/8/8/4K3 w - - 0 1", "d4e5", 200},
+		{"quiet move to an attacked square", "4k3/8/3p4/8/8/8/8/2N1K3 w - - 0 1", "c1e2", 0},
+		{"quiet move into a pawn", "4k3/8/3p4/8/8/1N6/8/4K3 w - - 0 1", "b3c5", -300},
+		{"en passant", "4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6", 100},
+		{"promotion", "4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a7a8q", 800},
+		{"defended promotion", "r3k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7a8q", 1300},
+		{"king recaptures", "3rk3/8/8/8/8/8/2Rr4/4K3 w - - 0 1", "c2d2", 500},
+		{"king can't recapture", "3rk3/8/8/8/5b2/8/2Rr4/4K3 w - - 0 1", "c2d2", 0},
+	}
+
+	for _, test := range tests {
+		pos, err := NewPositionFromFEN(test.fen)
+		assert.NoError(t, err)
+
+		move, err := pos.ParseMove(test.move)
+		assert.NoError(t, err)
+
+		assert.Equal(t, test.expected, pos.SEE(move), test.name)
+		assert.True(t, pos.SEEAtLeast(move, test.expected), test.name)
+		assert.False(t, pos.SEEAtLeast(move, test.expected+1), test.name)
+	}
+}
+
+// TestSEEAtLeast tests that SEEAtLeast agrees with SEE for every move and a range of thresholds in random games.
+func TestSEEAtLeast(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for _, fen := range legalMovePositions {
+		pos, err := NewPositionFromFEN(fen)
+		assert.NoError(t, err)
+
+		for ply := 0; ply < 100; ply++ {
+			moves := pos.MovesLegal().AsSlice()
+			if len(moves) == 0 {
+				break
+			}
+
+			for _, move := range moves {
+				see := pos.SEE(move)
+
+				for _, threshold := range []int16{see - 100, see - 1, see, see + 1, see + 100, -300, 0, 300} {
+					if !assert.Equal(t, see >= threshold, pos.SEEAtLeast(move, threshold), "SEEAtLeast(%s, %d) in %s with SEE %d", move, threshold, pos.StringFEN(), see) {
+						return
+					}
+				}
+			}
+
+			pos.MakeMove(moves[rng.Intn(len(moves))])
+		}
+	}
+}
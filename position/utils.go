@@ -8,3 +8,12 @@ func abs(x int) int {
 
 	return x
 }
+
+// max16 returns the larger of two int16s.
+func max16(a, b int16) int16 {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,116 @@
+package position
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by Validate, which say what kind of problem makes a position impossible. They are wrapped with more
+// detail, so use errors.Is to check for them.
+var (
+	ErrKingCount       = errors.New("each side must have exactly one king")
+	ErrPieceCount      = errors.New("each side can have at most 16 pieces and 8 pawns")
+	ErrPawnOnBackRank  = errors.New("pawns can't be on the first or last rank")
+	ErrOpponentInCheck = errors.New("the side not to move can't be in check")
+	ErrCastlingRights  = errors.New("castling needs the king and rook on the back rank")
+	ErrEnPassant       = errors.New("the en passant square must be behind a pawn that has just moved two squares")
+)
+
+// Validate checks that the position could be reached in a game of chess, or at least that it won't break move
+// generation and search. It doesn't check everything that would make a position unreachable, like pawn structures
+// that need more captures than there are missing pieces.
+func (p *Position) Validate() error {
+	for _, side := range []Color{White, Black} {
+		pieces := p.Occupied[side]
+
+		if kings := (pieces & p.Pieces[King]).Count(); kings != 1 {
+			return fmt.Errorf("%w, %s has %d", ErrKingCount, side, kings)
+		}
+
+		if p.Squares[p.KingLocation[side]] != King.OfColor(side) {
+			return fmt.Errorf("%w, %s's king isn't on %s", ErrKingCount, side, SquareToString(p.KingLocation[side]))
+		}
+
+		if count := pieces.Count(); count > 16 {
+			return fmt.Errorf("%w, %s has %d pieces", ErrPieceCount, side, count)
+		}
+
+		if pawns := (pieces & p.Pieces[Pawn]).Count(); pawns > 8 {
+			return fmt.Errorf("%w, %s has %d pawns", ErrPieceCount, side, pawns)
+		}
+	}
+
+	if pawns := p.Pieces[Pawn] & (maskRank1 | maskRank8); pawns != 0 {
+		return fmt.Errorf("%w, there is a pawn on %s", ErrPawnOnBackRank, SquareToString(pawns.FirstOn()))
+	}
+
+	if p.KingInCheck(p.SideToMove.Invert()) {
+		return fmt.Errorf("%w, %s is to move but %s is in check", ErrOpponentInCheck, p.SideToMove, p.SideToMove.Invert())
+	}
+
+	if err := p.validateCastling(); err != nil {
+		return err
+	}
+
+	return p.validateEnPassant()
+}
+
+// validateCastling checks that every side which can still castle has its king on the back rank and a rook on the
+// square it castles with.
+func (p *Position) validateCastling() error {
+	for i, right := range [4]CastlingAvailability{shortW, longW, shortB, longB} {
+		if p.Castling&right == 0 {
+			continue
+		}
+
+		side := White
+		backRank := uint8(0)
+
+		if right&(shortB|longB) != 0 {
+			side = Black
+			backRank = 7
+		}
+
+		if p.KingLocation[side]/8 != backRank {
+			return fmt.Errorf("%w, %s can castle but the king is on %s", ErrCastlingRights, side, SquareToString(p.KingLocation[side]))
+		}
+
+		rook := p.castlingRooks[i]
+		if rook/8 != backRank || p.Squares[rook] != Rook.OfColor(side) {
+			return fmt.Errorf("%w, %s can castle with the rook on %s but there isn't one", ErrCastlingRights, side, SquareToString(rook))
+		}
+	}
+
+	return nil
+}
+
+// validateEnPassant checks that the en passant target is on the square a pawn of the side not to move has just passed
+// over, which means the square it passed over and the square it started on are both empty.
+func (p *Position) validateEnPassant() error {
+	if !p.HasEnPassant() {
+		return nil
+	}
+
+	if p.EnPassant > 63 {
+		return fmt.Errorf("%w, %d is not a square", ErrEnPassant, p.EnPassant)
+	}
+
+	them := p.SideToMove.Invert()
+
+	// The pawn moved towards the side to move's end of the board, so for white to move it started on the seventh
+	// rank, passed over the sixth and is now on the fifth.
+	rank, direction := uint8(5), DirS
+	if p.SideToMove == Black {
+		rank, direction = 2, DirN
+	}
+
+	target := p.EnPassant
+	pawn := uint8(int(target) + direction)
+	start := uint8(int(target) - direction)
+
+	if target/8 != rank || p.Squares[pawn] != Pawn.OfColor(them) || !p.IsEmpty(target) || !p.IsEmpty(start) {
+		return fmt.Errorf("%w, there is no %s pawn that could have just passed over %s", ErrEnPassant, them, SquareToString(target))
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package position
+
+// Zobrist hashing gives each position a 64-bit key that can be updated incrementally as moves are made and unmade.
+// A random number is assigned to every (piece, square) pair, to black being the side to move, to each of the 16 possible
+// castling availabilities and to each file that an en passant target can be on. The key for a position is then all the
+// numbers that apply to it XORed together, which means adding or removing a feature is a single XOR.
+//
+// For more information: https://www.chessprogramming.org/Zobrist_Hashing
+
+var (
+	zobristPieces     [12][64]uint64 // zobristPieces holds a key for each colored piece on each square.
+	zobristSideToMove uint64         // zobristSideToMove is XORed into the hash when it's black's turn to move.
+	zobristCastling   [16]uint64     // zobristCastling holds a key for each possible CastlingAvailability.
+	zobristEnPassant  [8]uint64      // zobristEnPassant holds a key for the file of the en passant target square.
+)
+
+// zobristSeed is the seed used for generating the Zobrist keys. It is fixed so that hashes are the same every time the
+// program runs, which means they can be stored and compared between runs.
+const zobristSeed uint64 = 0x5EED_C4E5_5EED_C4E5
+
+// Hash returns the Zobrist hash of the position.
+func (p *Position) Hash() uint64 {
+	return p.hash
+}
+
+// ComputeHash calculates the Zobrist hash of the position from scratch rather than incrementally. It is much slower
+// than Hash but is useful for checking that the incremental updates haven't drifted.
+func (p *Position) ComputeHash() uint64 {
+	var hash uint64
+
+	for square, piece := range p.Squares {
+		if piece != Empty {
+			hash ^= zobristPieces[piece][square]
+		}
+	}
+
+	if p.SideToMove == Black {
+		hash ^= zobristSideToMove
+	}
+
+	hash ^= zobristCastling[p.Castling]
+
+	if p.HasEnPassant() {
+		hash ^= zobristEnPassant[p.EnPassant%8]
+	}
+
+	return hash
+}
+
+// setEnPassant sets the en passant target square, updating the hash to reflect the change.
+func (p *Position) setEnPassant(square uint8) {
+	if p.HasEnPassant() {
+		p.hash ^= zobristEnPassant[p.EnPassant%8]
+	}
+
+	p.EnPassant = square
+
+	if p.HasEnPassant() {
+		p.hash ^= zobristEnPassant[p.EnPassant%8]
+	}
+}
+
+// setCastling sets the castling availability, updating the hash to reflect the change.
+func (p *Position) setCastling(castling CastlingAvailability) {
+	p.hash ^= zobristCastling[p.Castling]
+	p.Castling = castling
+	p.hash ^= zobristCastling[p.Castling]
+}
+
+// splitMix64 is a small pseudorandom number generator used to initialise the Zobrist keys. It is used instead of
+// math/rand so that the keys are guaranteed not to change if the standard library implementation does.
+// https://prng.di.unimi.it/splitmix64.c
+type splitMix64 uint64
+
+func (s *splitMix64) next() uint64 {
+	*s += 0x9e3779b97f4a7c15
+
+	z := uint64(*s)
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+
+	return z ^ (z >> 31)
+}
+
+// initialiseZobrist fills the Zobrist key tables with pseudorandom numbers.
+func initialiseZobrist() {
+	rng := splitMix64(zobristSeed)
+
+	for piece := range zobristPieces {
+		for square := range zobristPieces[piece] {
+			zobristPieces[piece][square] = rng.next()
+		}
+	}
+
+	zobristSideToMove = rng.next()
+
+	for i := range zobristCastling {
+		zobristCastling[i] = rng.next()
+	}
+
+	for i := range zobristEnPassant {
+		zobristEnPassant[i] = rng.next()
+	}
+}
+
+func init() {
+	initialiseZobrist()
+}
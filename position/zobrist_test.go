@@ -0,0 +1,88 @@
+package position
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHashIncrementalMatchesComputed plays random games from a few positions and checks that the incrementally
+// updated hash always agrees with the hash calculated from scratch, both after making and after undoing moves.
+func TestHashIncrementalMatchesComputed(t *testing.T) {
+	tests := []string{
+		StartingPosition,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2",
+	}
+
+	rng := rand.New(rand.NewSource(1))
+
+	for _, fen := range tests {
+		for game := 0; game < 20; game++ {
+			pos, err := NewPositionFromFEN(fen)
+			assert.NoError(t, err)
+
+			assert.Equal(t, pos.ComputeHash(), pos.Hash(), "hash doesn't match after parsing %s", fen)
+
+			var played []Move
+
+			for ply := 0; ply < 80; ply++ {
+				moves := pos.MovesLegal().AsSlice()
+				if len(moves) == 0 {
+					break
+				}
+
+				move := moves[rng.Intn(len(moves))]
+				pos.MakeMove(move)
+				played = append(played, move)
+
+				if !assert.Equal(t, pos.ComputeHash(), pos.Hash(), "hash drifted after %s in %s", move.String(), pos.StringFEN()) {
+					return
+				}
+			}
+
+			for i := len(played) - 1; i >= 0; i-- {
+				pos.UndoMove(played[i])
+
+				if !assert.Equal(t, pos.ComputeHash(), pos.Hash(), "hash drifted after undoing %s in %s", played[i].String(), pos.StringFEN()) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// TestHashTransposition checks that reaching the same position through different move orders gives the same hash,
+// and that positions differing only in side to move, castling or en passant give different hashes.
+func TestHashTransposition(t *testing.T) {
+	a, _ := NewPositionFromFEN(StartingPosition)
+	b, _ := NewPositionFromFEN(StartingPosition)
+
+	for _, str := range []string{"g1f3", "g8f6", "b1c3", "b8c6"} {
+		move, _ := ParseMove(str)
+		a.MakeMove(NewMove(move.From(), move.To(), a.Squares[move.From()], a.Squares[move.To()], None, a.Castling, a.EnPassant))
+	}
+
+	for _, str := range []string{"b1c3", "b8c6", "g1f3", "g8f6"} {
+		move, _ := ParseMove(str)
+		b.MakeMove(NewMove(move.From(), move.To(), b.Squares[move.From()], b.Squares[move.To()], None, b.Castling, b.EnPassant))
+	}
+
+	assert.Equal(t, a.Hash(), b.Hash(), "expected transposed positions to have the same hash")
+
+	tests := [][2]string{
+		{"4k3/8/8/8/8/8/8/4K3 w - - 0 1", "4k3/8/8/8/8/8/8/4K3 b - - 0 1"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "r3k2r/8/8/8/8/8/8/R3K2R w KQk - 0 1"},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "4k3/8/8/3pP3/8/8/8/4K3 w - - 0 1"},
+	}
+
+	for _, test := range tests {
+		first, _ := NewPositionFromFEN(test[0])
+		second, _ := NewPositionFromFEN(test[1])
+
+		assert.NotEqual(t, first.Hash(), second.Hash(), "expected %s and %s to have different hashes", test[0], test[1])
+	}
+}
@@ -2,6 +2,10 @@ package search
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ollybritton/StupidChess/position"
@@ -15,19 +19,52 @@ type AlphaBetaSearch struct {
 	evalUs   position.Evaluator
 	evalThem position.Evaluator
 
-	startTime time.Time
-	nextTime  time.Time
-	nodeCount int
+	startTime  time.Time
+	nextTime   time.Time
+	nodeCount  int64 // nodeCount is the number of nodes visited in the main search, and is accessed atomically.
+	qNodeCount int64 // qNodeCount is the number of nodes visited in quiescence search, and is accessed atomically.
+	selDepth   int   // selDepth is the greatest ply reached, including quiescence search.
+
+	quiescenceChecks bool  // quiescenceChecks enables searching quiet checking moves at the first ply of quiescence.
+	contempt         int16 // contempt is how much worse than an equal position we consider a draw to be, in pawns.
+
+	tt   *TranspositionTable
+	ttUs position.Color // ttUs is the side we were playing when the entries in the transposition table were stored.
+
+	ordering *moveOrdering // ordering holds the killers, countermoves and history used to order quiet moves.
+
+	selectivity selectivity      // selectivity holds which pruning and reduction techniques are enabled.
+	stats       pruningStats     // stats counts how often each pruning and reduction technique was used.
+	noNullMove  [maxPly + 1]bool // noNullMove is true at plies where a null move isn't allowed, e.g. straight after another.
+
+	extensionStats extensionStats            // extensionStats counts how often each extension was used.
+	extended       [maxPly + 1]uint          // extended is the total extension of the line leading to each ply.
+	excluded       [maxPly + 1]position.Move // excluded is the move skipped at each ply while checking if it is singular.
+
+	helpers []*AlphaBetaSearch // helpers are the other threads searching the same position, see lazysmp.go.
+	helper  bool               // helper is true if this is a helper thread, which doesn't send any responses.
+
+	// stop is set to 1 when the search should stop as soon as possible. It is shared with the helper threads, so it is
+	// accessed atomically.
+	stop *uint32
+
+	// searching is held by Root while it handles a request, so that the options, transposition table and helpers
+	// aren't changed while the threads are using them.
+	searching sync.Mutex
 
 	options SearchOptions
 }
 
 func NewAlphaBetaSearch(requests chan Request, responses chan string, evalUs position.Evaluator, evalThem position.Evaluator) *AlphaBetaSearch {
 	return &AlphaBetaSearch{
-		requests:  requests,
-		responses: responses,
-		evalUs:    evalUs,
-		evalThem:  evalThem,
+		requests:    requests,
+		responses:   responses,
+		evalUs:      evalUs,
+		evalThem:    evalThem,
+		tt:          NewTranspositionTable(DefaultHashSize),
+		ordering:    &moveOrdering{},
+		selectivity: defaultSelectivity,
+		stop:        new(uint32),
 	}
 }
 
@@ -40,23 +77,147 @@ func (s *AlphaBetaSearch) Responses() chan string {
 }
 
 func (s *AlphaBetaSearch) Stop() {
-	s.options.Stop = true
+	atomic.StoreUint32(s.stop, 1)
 }
 
-func (s *AlphaBetaSearch) Root() error {
-	var pv pvList      // Holds the principle variation
-	var childPV pvList // Holds the principle variation of the position after the first move is made
+// waitForSearch stops any search that is running and waits for it to finish, returning with searching locked so that
+// another one can't start until the caller unlocks it. A search that Root has been sent but hasn't started yet is
+// stopped too, so it stops as soon as it starts.
+func (s *AlphaBetaSearch) waitForSearch() {
+	s.Stop()
+	s.searching.Lock()
+}
+
+// stopped returns true if the search has been told to stop, or has run out of time.
+func (s *AlphaBetaSearch) stopped() bool {
+	return atomic.LoadUint32(s.stop) != 0
+}
+
+// NewGame clears the transposition table and move ordering history so that results from a previous game don't affect
+// the next one. Any search still running is stopped first.
+func (s *AlphaBetaSearch) NewGame() {
+	s.waitForSearch()
+	defer s.searching.Unlock()
+
+	s.tt.Clear()
+	s.ordering.clear()
+
+	for _, helper := range s.helpers {
+		helper.ordering.clear()
+	}
+}
 
-	childPV.new()
+// Options returns the options that can be changed using the UCI "setoption" command.
+func (s *AlphaBetaSearch) Options() []Option {
+	options := []Option{
+		{Name: "Hash", Type: OptionSpin, Default: strconv.Itoa(DefaultHashSize), Min: MinHashSize, Max: MaxHashSize},
+		{Name: "Threads", Type: OptionSpin, Default: "1", Min: 1, Max: MaxThreads},
+		{Name: "Clear Hash", Type: OptionButton},
+		{Name: "QuiescenceChecks", Type: OptionCheck, Default: "false"},
+		{Name: "Contempt", Type: OptionSpin, Default: "0", Min: -maxContempt, Max: maxContempt},
+	}
+
+	for _, option := range selectivityOptions {
+		enabled := *option.enabled(&defaultSelectivity)
+		options = append(options, Option{Name: option.name, Type: OptionCheck, Default: strconv.FormatBool(enabled)})
+	}
+
+	return options
+}
+
+// SetOption changes one of the options returned by Options. Any search still running is stopped first.
+func (s *AlphaBetaSearch) SetOption(name, value string) error {
+	s.waitForSearch()
+	defer s.searching.Unlock()
+
+	switch strings.ToLower(name) {
+	case "hash":
+		megabytes, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("expecting number for option %q, got %q: %w", name, value, err)
+		}
+
+		if megabytes < MinHashSize || megabytes > MaxHashSize {
+			return fmt.Errorf("option %q must be between %d and %d, got %d", name, MinHashSize, MaxHashSize, megabytes)
+		}
+
+		s.tt.Resize(megabytes)
+
+	case "clear hash":
+		s.tt.Clear()
+
+	case "threads":
+		threads, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("expecting number for option %q, got %q: %w", name, value, err)
+		}
+
+		if threads < 1 || threads > MaxThreads {
+			return fmt.Errorf("option %q must be between %d and %d, got %d", name, 1, MaxThreads, threads)
+		}
 
+		s.setThreads(threads)
+
+	case "quiescencechecks":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("expecting true or false for option %q, got %q: %w", name, value, err)
+		}
+
+		s.quiescenceChecks = enabled
+
+	case "contempt":
+		contempt, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("expecting number for option %q, got %q: %w", name, value, err)
+		}
+
+		if contempt < -maxContempt || contempt > maxContempt {
+			return fmt.Errorf("option %q must be between %d and %d, got %d", name, -maxContempt, maxContempt, contempt)
+		}
+
+		s.contempt = contemptFromCentipawns(contempt)
+
+	default:
+		for _, option := range selectivityOptions {
+			if strings.EqualFold(name, option.name) {
+				enabled, err := strconv.ParseBool(value)
+				if err != nil {
+					return fmt.Errorf("expecting true or false for option %q, got %q: %w", name, value, err)
+				}
+
+				*option.enabled(&s.selectivity) = enabled
+				return nil
+			}
+		}
+
+		return fmt.Errorf("no such option %q", name)
+	}
+
+	return nil
+}
+
+func (s *AlphaBetaSearch) Root() error {
 	for request := range s.requests {
+		// Make sure we don't stop straight away if we were told to stop previously. This has to happen before waiting
+		// for the lock, since waitForSearch may have stopped this search while it waited and the stop would be lost.
+		atomic.StoreUint32(s.stop, 0)
+		s.searching.Lock()
+
 		pos := request.pos // Position we are searching
 
 		s.startTime = time.Now()    // Record start time so we know to stop if time is up
 		s.nextTime = time.Now()     // Record next time as a counter so we can periodically print information
-		s.nodeCount = 0             // Record number of nodes so we can stop after searching a certain number of nodes
 		s.options = request.options // Store options in the search struct so we don't have to explicitly pass around.
-		s.options.Stop = false      // Make sure we don't stop straight away if we were told to stop previously
+
+		// Record the number of nodes so we can stop after searching a certain number of nodes, and the number of
+		// quiescence nodes separately so we can see how much time is spent there.
+		atomic.StoreInt64(&s.nodeCount, 0)
+		atomic.StoreInt64(&s.qNodeCount, 0)
+
+		s.ordering.newSearch()
+		s.stats = pruningStats{}
+		s.extensionStats = extensionStats{}
 
 		var timeRemaining, increment time.Duration
 
@@ -74,150 +235,514 @@ func (s *AlphaBetaSearch) Root() error {
 			s.options.MoveTime = DefaultTimeManager(timeRemaining, increment)
 		}
 
+		// The evaluation can depend on which side we are playing, so entries stored while playing the other side can't
+		// be trusted.
+		if s.us != s.ttUs {
+			s.tt.Clear()
+			s.ttUs = s.us
+		}
+
+		s.tt.NewSearch()
+
 		s.responses <- fmt.Sprintf("info string searching for %s/%s (inc %s)", s.options.MoveTime, timeRemaining, increment)
 
-		// Keep track of the best move found so far. This is outside the loop so that we can return the best move found
-		// if we are asked to stop searching at a particular depth.
-		bestMove := position.NoMove
+		// Generate legal moves and order them so that captures come first. After the first iteration they are ordered by
+		// the score each move got instead.
+		legalMoves := pos.MovesLegal()
+		orderCaptures(legalMoves)
+
+		// With no legal moves the game is already over, so there is nothing to search. The score is reported from the
+		// point of view of the side to move, and "0000" is sent as the best move as the UCI protocol asks.
+		if legalMoves.Len() == 0 {
+			score := "cp 0"
+			if pos.KingInCheck(pos.SideToMove) {
+				score = "mate 0"
+			}
 
-		// Generate legal moves and annotate them with the evaluation after they've taken place so we can improve
-		// move ordering in the search.
-		legalMoves := pos.MovesLegalWithEvaluation(position.EvalSimple)
+			s.responses <- fmt.Sprintf("info depth 0 score %s", score)
+			s.responses <- "bestmove 0000"
+			s.searching.Unlock()
+			continue
+		}
 
-		// For loop for iterative deepening
-		for depth := uint(1); depth <= s.options.Depth; depth++ {
-			// Sort legal moves by the evaluation calculated above
-			legalMoves.Sort()
+		// If we've been asked to only consider certain moves, remove all the others.
+		if len(s.options.SearchMoves) != 0 {
+			legalMoves.Filter(func(move position.Move) bool {
+				for _, searchMove := range s.options.SearchMoves {
+					if move.Equal(searchMove) {
+						return true
+					}
+				}
 
-			// Best score for a move found so far
-			bestScore := position.NoEval
+				return false
+			})
+		}
 
-			for i, move := range legalMoves.AsSlice() {
-				// Alpha and beta
-				// Alpha here is the best score we can be guaranteed to achieve
-				// Beta here is the best score the opposing player can achieve
-				alpha, beta := position.MinEval, position.MaxEval
+		// The helper threads only make the search faster by filling the transposition table, so once this thread has
+		// finished they are stopped too.
+		var helpers sync.WaitGroup
+		s.startHelpers(&helpers, pos, legalMoves)
 
-				if s.options.Stop {
-					break
-				}
+		bestMove := s.iterativeDeepening(pos, legalMoves, 1)
 
-				// Clear the child PV so it can be used again for this move
-				childPV.clear()
+		s.Stop()
+		helpers.Wait()
 
-				// Make move, evaluate score of this position, and then undo move.
-				pos.MakeMove(move)
-				score := -s.search(-beta, -alpha, depth-1, 1, &childPV, pos)
-				pos.UndoMove(move)
+		// Like the node counts, the statistics are added up over every thread.
+		stats, extensions := s.stats, s.extensionStats
+		for _, helper := range s.helpers {
+			stats.add(helper.stats)
+			extensions.add(helper.extensionStats)
+		}
 
-				if s.options.Stop {
-					break
-				}
+		nodes, qNodes := s.nodes()
+		s.responses <- fmt.Sprintf("info string nodes %d qnodes %d threads %d", nodes, qNodes, len(s.helpers)+1)
+		s.responses <- fmt.Sprintf("info string pruning %s", stats)
+		s.responses <- fmt.Sprintf("info string extensions %s", extensions)
+		// If the search was stopped before it finished searching any move, the first move is better than nothing.
+		if bestMove == position.NoMove && legalMoves.Len() != 0 {
+			bestMove = legalMoves.Moves[0]
+		}
+
+		// Every move may have been filtered out by "searchmoves", in which case there is no move to send.
+		if bestMove == position.NoMove {
+			s.responses <- "bestmove 0000"
+		} else {
+			s.responses <- fmt.Sprintf("bestmove %s", bestMove.String())
+		}
 
-				// Store evaluation of this move so that on the next iteration the move ordering is more effective
-				move.SetEval(score)
-				legalMoves.Moves[i] = move
+		s.searching.Unlock()
+	}
+
+	return nil
+}
 
-				// If this is the best move we've seen so far...
-				if score > bestScore {
-					// Update bestScore to reflect this
-					bestScore = score
+// iterativeDeepening searches the legal moves in the position to each depth in turn, starting at startDepth, until it
+// reaches the depth limit or is stopped. It returns the best move found, or NoMove if there are no moves to search.
+func (s *AlphaBetaSearch) iterativeDeepening(pos *position.Position, legalMoves *position.MoveList, startDepth uint) position.Move {
+	if legalMoves.Len() == 0 {
+		return position.NoMove
+	}
 
-					// Update the principle variation to use this move instead
-					pv.clear()
-					pv.catenate(move, &childPV)
+	var pv pvList     // Holds the principle variation
+	var rootPV pvList // Holds the principle variation found by each search of the root moves
 
-					// Record this as the best move
-					bestMove = move
+	pv.new()
+	rootPV.new()
 
-					// Set alpha to this score
-					alpha = score
-				}
+	// Keep track of the best move found so far. This is outside the loop so that we can return the best move found
+	// if we are asked to stop searching at a particular depth.
+	bestMove := position.NoMove
+
+	// For loop for iterative deepening
+	prevScore := position.NoEval
 
-				s.responses <- fmt.Sprintf(
-					"info currmove %s currmovenumber %d nodes %d depth %d score cp %d",
-					move.String(),
-					i+1,
-					s.nodeCount,
-					depth,
-					score*100,
-				)
+	for depth := startDepth; depth <= s.options.Depth; depth++ {
+		// Reset the selective depth so it reflects this iteration only
+		s.selDepth = 0
+
+		// Search in a narrow window around the score from the last iteration, since the score is unlikely to change
+		// much and a narrow window gives more cutoffs. If the score falls outside the window, it is widened and the
+		// iteration searched again. Mate scores can change by a lot between iterations, so they use the full window.
+		alpha, beta := position.MinEval, position.MaxEval
+		delta := int(aspirationWindow)
+
+		if depth > 1 && prevScore > -mateThreshold && prevScore < mateThreshold {
+			alpha = clampEval(int(prevScore) - delta)
+			beta = clampEval(int(prevScore) + delta)
+		}
+
+		for {
+			// Sort legal moves by the evaluation calculated above, so that after a fail high the move that failed high
+			// is searched first.
+			legalMoves.Sort()
+
+			score, move := s.searchRoot(pos, legalMoves, alpha, beta, depth, &rootPV)
+
+			// A move which scores above alpha is better than any we knew about before, even if the iteration was
+			// stopped before every move was searched or the score is only a lower bound.
+			if move != position.NoMove && score > alpha {
+				bestMove = move
+				pv.clear()
+				pv.addPV(&rootPV)
 			}
 
-			if time.Since(s.startTime) > s.options.MoveTime {
+			if s.stopped() {
 				break
 			}
 
-			diff := time.Since(s.startTime)
-
-			if diff.Seconds() < 1 {
-				s.responses <- fmt.Sprintf(
-					"info depth %d score cp %d nodes %d time %d pv %s",
-					depth,
-					bestScore*100,
-					s.nodeCount,
-					diff.Milliseconds(),
-					pv.String(),
-				)
+			if score <= alpha && alpha > position.MinEval {
+				// Fail low: every move is worse than we expected, so the score is at most alpha and the best move
+				// can't be trusted. Lowering beta as well stops the re-search from failing high straight after.
+				s.sendInfo(depth, alpha, BoundUpper, nil)
+				beta = clampEval((int(alpha) + int(beta)) / 2)
+				alpha = clampEval(int(score) - delta)
+			} else if score >= beta && beta < position.MaxEval {
+				// Fail high: the best move is better than we expected, so the score is at least beta.
+				s.sendInfo(depth, beta, BoundLower, &pv)
+				beta = clampEval(int(score) + delta)
 			} else {
-				s.responses <- fmt.Sprintf(
-					"info depth %d score cp %d nodes %d nps %.0f time %d pv %s",
-					depth,
-					bestScore*100,
-					s.nodeCount,
-					1000*(float64(s.nodeCount)/float64(diff.Milliseconds())),
-					diff.Milliseconds(),
-					pv.String(),
-				)
-
+				s.tt.Store(pos.Hash(), depth, 0, score, BoundExact, bestMove)
+				s.sendInfo(depth, score, BoundExact, &pv)
+				prevScore = score
+				break
 			}
+
+			// Each time the score falls outside the window, the window grows more quickly so that large changes in
+			// the score don't need too many re-searches.
+			delta += delta
 		}
 
-		s.responses <- fmt.Sprintf("bestmove %s", bestMove.String())
+		if s.stopped() || time.Since(s.startTime) > s.options.MoveTime {
+			break
+		}
 	}
 
-	return nil
+	return bestMove
 }
 
-func (s *AlphaBetaSearch) search(alpha int16, beta int16, depth uint, ply int, pv *pvList, pos *position.Position) int16 {
-	s.nodeCount++
+// aspirationWindow is how far either side of the score from the last iteration the first search of each iteration
+// looks, in the same units as the evaluation.
+const aspirationWindow = 1
+
+// clampEval converts a score worked out using ints back into an int16, keeping it within the range of evaluations.
+func clampEval(score int) int16 {
+	if score < int(position.MinEval) {
+		return position.MinEval
+	} else if score > int(position.MaxEval) {
+		return position.MaxEval
+	}
 
-	// If we're at depth 0, stop recursing and instead return a static evaluation of this position.
-	if depth <= 0 {
-		if pos.SideToMove == s.us {
-			return position.ScoreFromPerspective(s.evalUs(pos), pos.SideToMove)
+	return int16(score)
+}
+
+// searchRoot searches every move at the root with the window (alpha, beta), using principal variation search: the
+// first move is searched with the full window, and the rest with a null window that only proves they are no better.
+// Only moves that turn out to be better are searched again with the full window.
+//
+// It returns the best score and move, and writes the principal variation of the best move to pv. If the best score is
+// at most alpha it is only an upper bound, and if it is at least beta it is only a lower bound.
+func (s *AlphaBetaSearch) searchRoot(pos *position.Position, moves *position.MoveList, alpha, beta int16, depth uint, pv *pvList) (int16, position.Move) {
+	bestMove, bestScore := position.NoMove, position.NoEval
+
+	var childPV pvList
+
+	for i, move := range moves.AsSlice() {
+		if s.stopped() {
+			break
+		}
+
+		// Clear the child PV so it can be used again for this move
+		childPV.clear()
+
+		// Make move, evaluate score of this position, and then undo move.
+		s.ordering.setMove(0, move)
+		s.extended[1] = 0
+		pos.MakeMove(move)
+
+		var score int16
+		if i == 0 {
+			score = -s.search(-beta, -alpha, depth-1, 1, &childPV, pos)
 		} else {
-			return position.ScoreFromPerspective(s.evalThem(pos), pos.SideToMove)
+			score = -s.search(-alpha-1, -alpha, depth-1, 1, &childPV, pos)
+
+			if score > alpha && score < beta && !s.stopped() {
+				childPV.clear()
+				score = -s.search(-beta, -alpha, depth-1, 1, &childPV, pos)
+			}
+		}
+
+		pos.UndoMove(move)
+
+		if s.stopped() {
+			break
+		}
+
+		// Store evaluation of this move so that on the next iteration the move ordering is more effective. Moves
+		// searched with a null window only get a bound, but this is still good enough to order them.
+		move.SetEval(score)
+		moves.Moves[i] = move
+
+		if !s.helper {
+			s.responses <- fmt.Sprintf(
+				"info currmove %s currmovenumber %d nodes %d depth %d",
+				move.String(),
+				i+1,
+				s.totalNodes(),
+				depth,
+			)
+		}
+
+		// If this is the best move we've seen so far, update the principle variation to use this move instead.
+		if score > bestScore {
+			bestScore = score
+			bestMove = move
+			pv.catenate(move, &childPV)
+		}
+
+		if score > alpha {
+			alpha = score
+		}
+
+		// The score is already above the window, so the other moves don't matter until it has been searched again.
+		if alpha >= beta {
+			break
 		}
 	}
 
+	return bestScore, bestMove
+}
+
+// sendInfo reports the result of searching an iteration to the given depth. If the search failed high or low, the
+// score is only a bound and pv may be nil. Helper threads don't report anything.
+func (s *AlphaBetaSearch) sendInfo(depth uint, score int16, bound Bound, pv *pvList) {
+	if s.helper {
+		return
+	}
+
+	diff := time.Since(s.startTime)
+
+	var out strings.Builder
+
+	fmt.Fprintf(&out, "info depth %d seldepth %d score %s", depth, s.selDepth, uciScore(score))
+
+	switch bound {
+	case BoundLower:
+		out.WriteString(" lowerbound")
+	case BoundUpper:
+		out.WriteString(" upperbound")
+	}
+
+	// The nodes and nps include every thread, since they are all working on the same search.
+	nodes := s.totalNodes()
+
+	fmt.Fprintf(&out, " nodes %d", nodes)
+
+	if diff.Seconds() >= 1 {
+		fmt.Fprintf(&out, " nps %.0f", 1000*(float64(nodes)/float64(diff.Milliseconds())))
+	}
+
+	fmt.Fprintf(&out, " hashfull %d time %d", s.tt.Hashfull(), diff.Milliseconds())
+
+	if pv != nil && len(*pv) != 0 {
+		fmt.Fprintf(&out, " pv %s", pv.String())
+	}
+
+	s.responses <- out.String()
+}
+
+// uciScore returns a score as it is written in a UCI "info" command, either "cp" followed by the score in centipawns or
+// "mate" followed by the number of moves until mate, which is negative if the side to move is being mated.
+func uciScore(score int16) string {
+	switch {
+	case score > mateThreshold:
+		// Giving mate on the ply p from the root scores MaxEval-p-1, and p is odd since the side to move gives mate.
+		return fmt.Sprintf("mate %d", (int(position.MaxEval)-int(score))/2)
+	case score < -mateThreshold:
+		// Being mated on the ply p from the root scores MinEval+p+1, and p is even.
+		return fmt.Sprintf("mate %d", -(int(score)-int(position.MinEval)-1)/2)
+	}
+
+	// Scores are in pawns, but UCI expects them in centipawns. This is worked out as an int so that it can't overflow.
+	return fmt.Sprintf("cp %d", int(score)*100)
+}
+
+func (s *AlphaBetaSearch) search(alpha int16, beta int16, depth uint, ply int, pv *pvList, pos *position.Position) int16 {
+	// Positions that have occurred before are scored as draws. If repeating the position once was the best thing to do,
+	// then it can be repeated again to reach a threefold repetition, so there's no need to wait for the third time.
+	// The game history is included since the position keeps track of every position before it. Positions where neither
+	// side has the pieces left to checkmate are draws however they are played.
+	if pos.IsRepetition() || pos.IsInsufficientMaterial() {
+		pv.clear()
+		return s.drawScore(pos)
+	}
+
+	// Checkmate takes priority over the fifty move rule, so when in check the position is only a draw if there is a
+	// legal move. Moves are only generated in that case since it's rare.
+	if pos.IsFiftyMoveDraw() && (!pos.KingInCheck(pos.SideToMove) || pos.MovesLegal().Len() > 0) {
+		pv.clear()
+		return s.drawScore(pos)
+	}
+
+	// If we're at depth 0, stop recursing and instead search captures until the position is quiet. Extensions can make
+	// lines longer than the depth, so the search also stops if it runs out of plies.
+	if depth <= 0 || ply >= maxPly {
+		pv.clear()
+		return s.quiescence(alpha, beta, 0, ply, pos)
+	}
+
+	atomic.AddInt64(&s.nodeCount, 1)
+
+	if ply > s.selDepth {
+		s.selDepth = ply
+	}
+
 	// Clear the principle variation
 	pv.clear()
 
-	// Generate all legal moves in this position
-	legalMoves := pos.MovesLegalWithEvaluation(position.EvalSimple)
-	legalMoves.Sort()
+	// Mate distance pruning: even mating straight away can't score better than mate at the next ply, and being mated
+	// here is the worst that can happen. If a shorter mate has already been found, nothing here can improve on it.
+	if s.selectivity.mateDistance {
+		if mated := matedScore(ply); alpha < mated {
+			alpha = mated
+		}
+
+		if mating := -matedScore(ply + 1); beta > mating {
+			beta = mating
+		}
+
+		if alpha >= beta {
+			s.stats.mateDistanceCuts++
+			return alpha
+		}
+	}
+
+	// Positions searched with a null window only need to show whether the score is above or below it, so they can be
+	// pruned more aggressively than positions on the principal variation, whose exact score is needed.
+	pvNode := int(beta)-int(alpha) > 1
+
+	// Remember alpha as it was passed in so that we know what kind of bound the score is when storing it in the
+	// transposition table.
+	alphaOriginal := alpha
+
+	// Look up the position in the transposition table. If it has already been searched to at least this depth, the
+	// stored score may be enough to return straight away. Otherwise the stored move is still a good guess at the best
+	// move, so it's searched first.
+	//
+	// While checking whether a move is singular, the position is searched without that move, so the stored result for
+	// the position as a whole doesn't apply.
+	hashMove := position.NoMove
+	excluded := s.excluded[ply]
+
+	entry, ttHit := s.tt.Probe(pos.Hash())
+	if ttHit && excluded == position.NoMove {
+		hashMove = entry.move
+
+		if uint(entry.depth) >= depth {
+			score := scoreFromTT(entry.score, ply)
+
+			switch {
+			case entry.bound == BoundExact,
+				entry.bound == BoundLower && score >= beta,
+				entry.bound == BoundUpper && score <= alpha:
+				return score
+			}
+		}
+	}
+
+	inCheck := pos.KingInCheck(pos.SideToMove)
+
+	// The static evaluation is used to guess whether the position is so far outside the window that it isn't worth
+	// searching fully. When in check it is meaningless, since there may be no good way out.
+	staticEval := position.NoEval
+	if !inCheck {
+		staticEval = s.evaluate(pos)
+	}
+
+	if !pvNode && !inCheck && excluded == position.NoMove {
+		if score, ok := s.prune(alpha, beta, depth, ply, staticEval, pos); ok {
+			return score
+		}
+	}
+
+	// Moves are generated in stages by the move picker, so if the hash move or a good capture causes a cutoff then the
+	// quiet moves are never generated.
+	picker := newMovePicker(pos, hashMove, s.ordering, ply, inCheck)
+	singular := ttHit && s.isSingular(entry, depth, ply, pos)
+	killers := s.ordering.killersAt(ply)
 
 	// Initialise bestMove and bestScore to hold the best move found so far.
 	bestMove, bestScore := position.NoMove, position.NoEval
+	moveCount := 0
 
-	// TODO: doesn't yet understand draw by threefold repetition
+	// The quiet moves searched before a cutoff have their history scores lowered, since they weren't good enough.
+	var quietsTried [64]position.Move
+	quietCount := 0
 
 	var childPV pvList
 
-	for _, move := range legalMoves.AsSlice() {
+	for {
+		move, ok := picker.next()
+		if !ok {
+			break
+		}
+
+		if move.Equal(excluded) {
+			continue
+		}
+
+		moveCount++
 		childPV.clear()
 
+		s.ordering.setMove(ply, move)
 		pos.MakeMove(move)
-		score := -s.search(-beta, -alpha, depth-1, ply+1, &childPV, pos)
+
+		// Quiet moves are the ones pruned and reduced, since captures, promotions, checks and escapes from check are
+		// the moves most likely to change the score by a lot.
+		quiet := !inCheck && !isNoisy(move) && !pos.KingInCheck(pos.SideToMove)
+
+		// Extensions: moves starting a forcing line are searched more deeply, and aren't pruned or reduced.
+		extension := s.extension(move, ply, pvNode, singular && move.Equal(hashMove), pos)
+		newDepth := depth - 1 + extension
+		s.extended[ply+1] = s.extended[ply] + extension
+
+		// Futility pruning: near the leaves, a quiet move is very unlikely to make up for a static evaluation far below
+		// alpha, so it is skipped. The first move is always searched so that there is a score to return.
+		if s.selectivity.futility && quiet && extension == 0 && !pvNode && moveCount > 1 && depth <= futilityMaxDepth && alpha > -mateThreshold {
+			if futile := clampEval(int(staticEval) + futilityMargin*int(depth)); futile <= alpha {
+				pos.UndoMove(move)
+				s.stats.futilityPrunes++
+
+				if futile > bestScore {
+					bestScore = futile
+				}
+
+				continue
+			}
+		}
+
+		// Late move reductions: with good move ordering, quiet moves near the end of the list are rarely the best, so
+		// they are searched less deeply. If one turns out to be better than alpha, it is searched again at full depth.
+		reduction := uint(0)
+		if s.selectivity.lateMoves && quiet && extension == 0 && depth >= lateMoveMinDepth && moveCount >= lateMoveMinCount && !move.Equal(killers[0]) && !move.Equal(killers[1]) {
+			reduction = lateMoveReduction(depth, moveCount, pvNode)
+			s.stats.lateMoveReductions++
+		}
+
+		// Principal variation search: after the first move, moves are searched with a null window that can only show
+		// whether they are better than alpha. That's cheaper than a full search, and if the first move is the best, as
+		// it usually is with good move ordering, it's all that's needed. Moves that turn out to be better are searched
+		// again with the full window to find their exact score.
+		var score int16
+		if moveCount == 1 {
+			score = -s.search(-beta, -alpha, newDepth, ply+1, &childPV, pos)
+		} else {
+			score = -s.search(-alpha-1, -alpha, newDepth-reduction, ply+1, &childPV, pos)
+
+			if reduction > 0 && score > alpha {
+				s.stats.lateMoveResearches++
+				childPV.clear()
+				score = -s.search(-alpha-1, -alpha, newDepth, ply+1, &childPV, pos)
+			}
+
+			if score > alpha && score < beta {
+				childPV.clear()
+				score = -s.search(-beta, -alpha, newDepth, ply+1, &childPV, pos)
+			}
+		}
+
 		pos.UndoMove(move)
 
+		if !isNoisy(move) && quietCount < len(quietsTried) {
+			quietsTried[quietCount] = move
+			quietCount++
+		}
+
 		// If this is the best score we've found so far...
 		if score > bestScore {
 			// Update bestScore and bestMove to track this (might not need bestMove)
 			bestScore = score
 			bestMove = move
-			_ = bestMove
 
 			// Add this to the principle variation
 			pv.catenate(move, &childPV)
@@ -231,7 +756,13 @@ func (s *AlphaBetaSearch) search(alpha int16, beta int16, depth uint, ply int, p
 
 		// Beta cutoff:
 		// The opposing player can guarantee a better position for themselves, so there's no point pursuing this position.
+		// Quiet moves that cause a cutoff are remembered as killers and countermoves and given a higher history score,
+		// since they will often cause a cutoff in similar positions.
 		if alpha >= beta {
+			if !isNoisy(move) {
+				s.ordering.update(pos.SideToMove, move, quietsTried[:quietCount], depth, ply)
+			}
+
 			break
 		}
 
@@ -243,11 +774,11 @@ func (s *AlphaBetaSearch) search(alpha int16, beta int16, depth uint, ply int, p
 		}
 
 		if time.Since(s.startTime) > s.options.MoveTime {
-			s.options.Stop = true
+			s.Stop()
 		}
 
 		// If required to stop early, return alpha since this is the best we can do.
-		if s.options.Stop {
+		if s.stopped() {
 			return alpha
 		}
 
@@ -255,15 +786,143 @@ func (s *AlphaBetaSearch) search(alpha int16, beta int16, depth uint, ply int, p
 
 	// If we have no moves available, it's either checkmate or stalemate, so return values
 	// that reflect this.
-	if legalMoves.Len() == 0 {
-		if pos.KingInCheck(pos.SideToMove) {
+	if moveCount == 0 {
+		// The only move was the one excluded while checking if it is singular, so it certainly is.
+		if excluded != position.NoMove {
+			return alpha
+		}
+
+		if inCheck {
 			// Checkmate
-			return -30000 + int16(ply) + 1
+			return matedScore(ply)
 		}
 
 		// Stalemate
-		return 0 // TODO: return contempt value instead?
+		return s.drawScore(pos)
+	}
+
+	bound := BoundExact
+	if bestScore <= alphaOriginal {
+		// None of the moves raised alpha, so we only know that the true score is at most this and the best move is
+		// unreliable.
+		bound = BoundUpper
+		bestMove = position.NoMove
+	} else if bestScore >= beta {
+		bound = BoundLower
+	}
+
+	if excluded == position.NoMove {
+		s.tt.Store(pos.Hash(), depth, ply, bestScore, bound, bestMove)
 	}
 
 	return bestScore
 }
+
+// prune tries the techniques that cut off a whole position without searching any moves: reverse futility pruning,
+// razoring and null move pruning. It returns the score to return and true if the position can be cut off. It is only
+// used away from the principal variation and when not in check.
+func (s *AlphaBetaSearch) prune(alpha, beta int16, depth uint, ply int, staticEval int16, pos *position.Position) (int16, bool) {
+	// None of these can prove a mate, so they aren't used when the window is around a mate score.
+	if beta >= mateThreshold || alpha <= -mateThreshold {
+		return 0, false
+	}
+
+	// Reverse futility pruning: if the static evaluation is far enough above beta, the opponent is unlikely to be able
+	// to do anything about it in the few plies left.
+	if s.selectivity.reverseFutility && depth <= reverseFutilityMaxDepth {
+		if int(staticEval)-reverseFutilityMargin*int(depth) >= int(beta) {
+			s.stats.reverseFutilityCuts++
+			return staticEval, true
+		}
+	}
+
+	// Razoring: if the static evaluation is far below alpha near the leaves, only captures are likely to help, so
+	// quiescence search is used to check. If even that can't get above alpha, the position is cut off.
+	if s.selectivity.razoring && depth <= razoringMaxDepth && int(staticEval)+razoringMargin*int(depth) <= int(alpha) {
+		if score := s.quiescence(alpha, alpha+1, 0, ply, pos); score <= alpha {
+			s.stats.razoringCuts++
+			return score, true
+		}
+	}
+
+	// Null move pruning: if the side to move could pass and still be above beta after a shallower search, then one of
+	// its real moves almost certainly is too. This relies on there always being a move better than passing, which
+	// isn't true in zugzwang, so it isn't used straight after another null move or without any pieces other than
+	// pawns. Deep cutoffs are also checked with a shallower search of the real moves.
+	if s.selectivity.nullMove && depth >= nullMoveMinDepth && staticEval >= beta && ply < maxPly && !s.noNullMove[ply] && hasNonPawnMaterial(pos) {
+		var nullPV pvList
+
+		s.stats.nullMoveTries++
+
+		s.ordering.setMove(ply, position.NoMove)
+		s.extended[ply+1] = s.extended[ply]
+		pos.MakeNullMove()
+		s.noNullMove[ply+1] = true
+		score := -s.search(-beta, -beta+1, depth-1-nullMoveReduction, ply+1, &nullPV, pos)
+		s.noNullMove[ply+1] = false
+		pos.Unmake()
+
+		if s.stopped() || score < beta {
+			return 0, false
+		}
+
+		// A mate found after passing isn't a real mate, so it isn't returned.
+		if score >= mateThreshold {
+			score = beta
+		}
+
+		if depth >= nullMoveVerifyDepth {
+			s.noNullMove[ply] = true
+			verified := s.search(beta-1, beta, depth-nullMoveReduction, ply, &nullPV, pos)
+			s.noNullMove[ply] = false
+
+			if verified < beta {
+				return 0, false
+			}
+		}
+
+		s.stats.nullMoveCutoffs++
+		return score, true
+	}
+
+	return 0, false
+}
+
+// evaluate returns the static evaluation of the position from the perspective of the side to move, using the evaluator
+// for whichever side that is.
+func (s *AlphaBetaSearch) evaluate(pos *position.Position) int16 {
+	if pos.SideToMove == s.us {
+		return position.ScoreFromPerspective(s.evalUs(pos), pos.SideToMove)
+	}
+
+	return position.ScoreFromPerspective(s.evalThem(pos), pos.SideToMove)
+}
+
+// maxContempt is the largest contempt that can be set, in centipawns like every other score sent over UCI.
+const maxContempt = 1000
+
+// contemptFromCentipawns converts a contempt given in centipawns to the units of the evaluation, which are whole pawns.
+// It is rounded to the nearest pawn with halves rounded away from zero, so e.g. 49 has no effect and 50 is one pawn.
+func contemptFromCentipawns(centipawns int) int16 {
+	if centipawns < 0 {
+		return -contemptFromCentipawns(-centipawns)
+	}
+
+	return int16((centipawns + 50) / 100)
+}
+
+// drawScore returns the score of a draw from the perspective of the side to move. A positive contempt means that we
+// think a draw is worse for us than an equal position, so we will avoid one unless we are losing.
+func (s *AlphaBetaSearch) drawScore(pos *position.Position) int16 {
+	if pos.SideToMove == s.us {
+		return -s.contempt
+	}
+
+	return s.contempt
+}
+
+// matedScore returns the score for the side to move being checkmated at the given ply. Mates closer to the root score
+// lower, so that the search prefers delivering mate sooner and being mated later.
+func matedScore(ply int) int16 {
+	return position.MinEval + int16(ply) + 1
+}
@@ -0,0 +1,169 @@
+package search
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ollybritton/StupidChess/position"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestUCIScore tests that scores are converted to centipawns without overflowing, and that mate scores are given as
+// the number of moves until mate.
+func TestUCIScore(t *testing.T) {
+	tests := []struct {
+		score int16
+		want  string
+	}{
+		{0, "cp 0"},
+		{3, "cp 300"},
+		{-9, "cp -900"},
+		{mateThreshold, "cp 2987200"},
+		{-matedScore(1), "mate 1"},
+		{-matedScore(3), "mate 2"},
+		{matedScore(2), "mate -1"},
+		{matedScore(4), "mate -2"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, uciScore(test.score), "wrong UCI score for %d", test.score)
+	}
+}
+
+// searchResponses runs the search on the position to the given depth, returning every response sent up to and
+// including the best move.
+func searchResponses(t *testing.T, s *AlphaBetaSearch, fen string, depth uint) []string {
+	pos, err := position.NewPositionFromFEN(fen)
+	assert.NoError(t, err)
+
+	options := NewDeafultOptions()
+	options.Depth = depth
+	s.requests <- NewRequest(pos, options)
+
+	var responses []string
+	for response := range s.responses {
+		responses = append(responses, response)
+
+		if strings.HasPrefix(response, "bestmove") {
+			break
+		}
+	}
+
+	return responses
+}
+
+// searchToDepth runs the search on the position to the given depth, returning the last "info depth" line sent and the
+// best move.
+func searchToDepth(t *testing.T, s *AlphaBetaSearch, fen string, depth uint) (string, string) {
+	var last, bestMove string
+
+	for _, response := range searchResponses(t, s, fen, depth) {
+		if strings.HasPrefix(response, "bestmove") {
+			bestMove = strings.TrimPrefix(response, "bestmove ")
+		}
+
+		if strings.HasPrefix(response, "info depth") {
+			last = response
+		}
+	}
+
+	return last, bestMove
+}
+
+// TestAlphaBetaSearchMate tests that the search finds a forced mate and reports it with the right number of moves,
+// both with every pruning technique enabled and with each one turned off.
+func TestAlphaBetaSearchMate(t *testing.T) {
+	s := NewAlphaBetaSearch(make(chan Request), make(chan string), position.EvalSimple, position.EvalSimple)
+	go s.Root()
+	defer close(s.requests)
+
+	names := []string{""}
+	for _, option := range selectivityOptions {
+		names = append(names, option.name)
+	}
+
+	for _, name := range names {
+		if name != "" {
+			assert.NoError(t, s.SetOption(name, "false"))
+		}
+
+		last, _ := searchToDepth(t, s, "7k/8/8/8/8/8/R7/1R4K1 w - - 0 1", 4)
+		assert.Contains(t, last, "depth 4", "with %q off", name)
+		assert.Contains(t, last, "score mate 2", "with %q off", name)
+		assert.NotContains(t, last, "bound", "with %q off", name)
+
+		if name != "" {
+			assert.NoError(t, s.SetOption(name, "true"))
+		}
+	}
+
+	assert.Error(t, s.SetOption("NullMovePruning", "sometimes"))
+}
+
+// TestAlphaBetaSearchNoMoves tests that when the side to move is already checkmated or stalemated, the search reports
+// the result straight away and sends "0000" as the best move.
+func TestAlphaBetaSearchNoMoves(t *testing.T) {
+	s := NewAlphaBetaSearch(make(chan Request), make(chan string), position.EvalSimple, position.EvalSimple)
+	go s.Root()
+	defer close(s.requests)
+
+	tests := []struct {
+		fen   string
+		score string
+	}{
+		{"7k/6Q1/6K1/8/8/8/8/8 b - - 0 1", "score mate 0"},
+		{"7k/5Q2/6K1/8/8/8/8/8 b - - 0 1", "score cp 0"},
+	}
+
+	for _, test := range tests {
+		last, bestMove := searchToDepth(t, s, test.fen, 3)
+		assert.Equal(t, "info depth 0 "+test.score, last, "in %s", test.fen)
+		assert.Equal(t, "0000", bestMove, "in %s", test.fen)
+
+		pos, err := position.NewPositionFromFEN(test.fen)
+		assert.NoError(t, err)
+
+		_, ok := s.tt.Probe(pos.Hash())
+		assert.False(t, ok, "stored entry for %s", test.fen)
+	}
+}
+
+// TestAlphaBetaSearchFiftyMoveMate tests that a checkmate given by the move that reaches the fifty move limit is scored
+// as a mate rather than a draw.
+func TestAlphaBetaSearchFiftyMoveMate(t *testing.T) {
+	s := NewAlphaBetaSearch(make(chan Request), make(chan string), position.EvalSimple, position.EvalSimple)
+	go s.Root()
+	defer close(s.requests)
+
+	last, bestMove := searchToDepth(t, s, "7k/8/6K1/8/8/8/Q7/8 w - - 99 80", 2)
+	assert.Contains(t, last, "score mate 1")
+	assert.Equal(t, "a2a8", bestMove)
+}
+
+// TestContemptOption tests that contempt is set in centipawns and rounded to the nearest pawn.
+func TestContemptOption(t *testing.T) {
+	s := NewAlphaBetaSearch(make(chan Request), make(chan string), position.EvalSimple, position.EvalSimple)
+
+	tests := []struct {
+		centipawns string
+		want       int16
+	}{
+		{"0", 0},
+		{"20", 0},
+		{"50", 1},
+		{"149", 1},
+		{"250", 3},
+		{"-20", 0},
+		{"-50", -1},
+		{"-1000", -10},
+	}
+
+	for _, test := range tests {
+		assert.NoError(t, s.SetOption("Contempt", test.centipawns))
+		assert.Equal(t, test.want, s.contempt, "contempt for %s centipawns", test.centipawns)
+	}
+
+	assert.Error(t, s.SetOption("Contempt", "1001"))
+	assert.Error(t, s.SetOption("Contempt", "-1001"))
+	assert.Error(t, s.SetOption("Contempt", "lots"))
+}
@@ -0,0 +1,135 @@
+package search
+
+import (
+	"fmt"
+
+	"github.com/ollybritton/StupidChess/position"
+)
+
+// Extensions are the opposite of reductions: some moves start forcing lines where the outcome is only clear a few plies
+// later, so they are searched one ply more deeply than the others. Since an extended line can be extended again, the
+// total extension along any line is capped so that the search can't explode.
+//
+// For more information: https://www.chessprogramming.org/Extensions
+
+// Limits for the extensions, in plies of depth and the same units as the evaluation.
+const (
+	maxExtensions       = 8 // maxExtensions is the most plies any line can be extended by in total.
+	singularMinDepth    = 6 // singularMinDepth is the least depth at which the hash move is checked for being singular.
+	singularDepthMargin = 3 // singularDepthMargin is how much shallower than the current depth the hash entry may be.
+	singularMargin      = 1 // singularMargin is how far below the hash move's score every other move must be.
+)
+
+// extensionStats counts how often each extension was used in a search.
+type extensionStats struct {
+	checks      int // checks is the number of moves extended for giving check.
+	passedPawns int // passedPawns is the number of pawn pushes to the seventh rank extended.
+	recaptures  int // recaptures is the number of recaptures extended.
+	singular    int // singular is the number of singular hash moves extended.
+	capped      int // capped is the number of extensions not made because the line had already been extended enough.
+}
+
+// String returns the statistics as they are sent in a UCI "info string" command.
+func (e extensionStats) String() string {
+	return fmt.Sprintf(
+		"check %d passedpawn %d recapture %d singular %d capped %d",
+		e.checks,
+		e.passedPawns,
+		e.recaptures,
+		e.singular,
+		e.capped,
+	)
+}
+
+// add adds the statistics from another thread's search.
+func (e *extensionStats) add(other extensionStats) {
+	e.checks += other.checks
+	e.passedPawns += other.passedPawns
+	e.recaptures += other.recaptures
+	e.singular += other.singular
+	e.capped += other.capped
+}
+
+// extension returns how many plies more deeply to search a move, which has already been made on pos. singular is true
+// if the move is the hash move and isSingular found it to be singular.
+func (s *AlphaBetaSearch) extension(move position.Move, ply int, pvNode, singular bool, pos *position.Position) uint {
+	var stat *int
+
+	switch {
+	case singular:
+		stat = &s.extensionStats.singular
+
+	case s.selectivity.checkExtension && pos.KingInCheck(pos.SideToMove):
+		stat = &s.extensionStats.checks
+
+	case s.selectivity.passedPawnExtension && isPawnPushToSeventh(move):
+		stat = &s.extensionStats.passedPawns
+
+	// Recaptures are only extended on the principal variation, since otherwise every exchange would be.
+	case s.selectivity.recaptureExtension && pvNode && isRecapture(move, s.ordering.previous(ply, 1)):
+		stat = &s.extensionStats.recaptures
+
+	default:
+		return 0
+	}
+
+	if s.extended[ply] >= maxExtensions {
+		s.extensionStats.capped++
+		return 0
+	}
+
+	*stat++
+	return 1
+}
+
+// isPawnPushToSeventh returns true if the move is a pawn moving to the rank before it promotes. There can't be any enemy
+// pawns in front of it on the same or neighbouring files, so it is always a passed pawn.
+func isPawnPushToSeventh(move position.Move) bool {
+	moved := move.Moved()
+	if moved.Colorless() != position.Pawn {
+		return false
+	}
+
+	if moved.Color() == position.White {
+		return position.OnRank(move.To(), 7)
+	}
+
+	return position.OnRank(move.To(), 2)
+}
+
+// isRecapture returns true if the move captures the piece that made the previous move, which was also a capture.
+func isRecapture(move, previous position.Move) bool {
+	return move.IsCapture() && previous != position.NoMove && previous.IsCapture() && move.To() == previous.To()
+}
+
+// isSingular returns true if the hash move looks much better than every other move in the position, since searching
+// the others to a reduced depth shows that none of them come close to the score stored for the hash move. A move like
+// this is often the only way to hold the position, so if the search is wrong about it the score will be badly wrong,
+// which makes it worth searching more deeply.
+//
+// This can only be checked when the hash entry is a lower bound or exact score from a search that isn't too much
+// shallower than this one.
+func (s *AlphaBetaSearch) isSingular(entry ttEntry, depth uint, ply int, pos *position.Position) bool {
+	if !s.selectivity.singularExtension || depth < singularMinDepth || s.excluded[ply] != position.NoMove {
+		return false
+	}
+
+	if entry.move == position.NoMove || entry.bound == BoundUpper || uint(entry.depth)+singularDepthMargin < depth {
+		return false
+	}
+
+	score := scoreFromTT(entry.score, ply)
+	if score >= mateThreshold || score <= -mateThreshold {
+		return false
+	}
+
+	var singularPV pvList
+
+	singularBeta := score - singularMargin
+
+	s.excluded[ply] = entry.move
+	result := s.search(singularBeta-1, singularBeta, depth/2, ply, &singularPV, pos)
+	s.excluded[ply] = position.NoMove
+
+	return !s.stopped() && result < singularBeta
+}
@@ -0,0 +1,43 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/ollybritton/StupidChess/position"
+	"github.com/stretchr/testify/assert"
+)
+
+// findMove returns the legal move in the position with the given UCI string.
+func findMove(t *testing.T, pos *position.Position, uci string) position.Move {
+	for _, move := range pos.MovesLegal().AsSlice() {
+		if move.String() == uci {
+			return move
+		}
+	}
+
+	t.Fatalf("no legal move %s in %s", uci, pos.StringFEN())
+	return position.NoMove
+}
+
+// TestExtensionMoves tests that pawn pushes to the seventh rank and recaptures are recognised for either side.
+func TestExtensionMoves(t *testing.T) {
+	white, err := position.NewPositionFromFEN("4k3/8/1P6/8/8/2p5/8/4K3 w - - 0 1")
+	assert.NoError(t, err)
+
+	black, err := position.NewPositionFromFEN("4k3/8/1P6/8/8/2p5/8/4K3 b - - 0 1")
+	assert.NoError(t, err)
+
+	assert.True(t, isPawnPushToSeventh(findMove(t, white, "b6b7")))
+	assert.True(t, isPawnPushToSeventh(findMove(t, black, "c3c2")))
+	assert.False(t, isPawnPushToSeventh(findMove(t, white, "e1f1")))
+	assert.False(t, isPawnPushToSeventh(findMove(t, black, "e8f8")))
+
+	pos, err := position.NewPositionFromFEN("4k3/4n3/8/3p4/4P3/8/8/4K3 w - - 0 1")
+	assert.NoError(t, err)
+
+	capture := findMove(t, pos, "e4d5")
+	pos.MakeMove(capture)
+	assert.True(t, isRecapture(findMove(t, pos, "e7d5"), capture))
+	assert.False(t, isRecapture(findMove(t, pos, "e7d5"), position.NoMove))
+	assert.False(t, isRecapture(findMove(t, pos, "e7c6"), capture))
+}
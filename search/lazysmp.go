@@ -0,0 +1,88 @@
+package search
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/ollybritton/StupidChess/position"
+)
+
+// With more than one thread, the search uses Lazy SMP: helper threads search the same position as the main thread at
+// the same time, sharing only the transposition table. They don't report anything or choose the best move, but the
+// positions they store in the table let the main thread cut off or order moves it would otherwise have had to search,
+// and since they don't coordinate with each other, they often end up searching different parts of the tree.
+//
+// For more information: https://www.chessprogramming.org/Lazy_SMP
+
+// MaxThreads is the largest number of threads the search can be set to use.
+const MaxThreads = 256
+
+// setThreads changes the number of threads searching, including the main thread. Helpers that are kept keep their
+// move ordering history.
+func (s *AlphaBetaSearch) setThreads(threads int) {
+	for len(s.helpers) < threads-1 {
+		s.helpers = append(s.helpers, &AlphaBetaSearch{
+			evalUs:   s.evalUs,
+			evalThem: s.evalThem,
+			tt:       s.tt,
+			ordering: &moveOrdering{},
+			helper:   true,
+			stop:     s.stop,
+		})
+	}
+
+	s.helpers = s.helpers[:threads-1]
+}
+
+// startHelpers starts every helper thread searching the position with the same options as the main thread. Each one
+// gets its own copy of the position and root moves, and wg is done once they have all stopped.
+func (s *AlphaBetaSearch) startHelpers(wg *sync.WaitGroup, pos *position.Position, legalMoves *position.MoveList) {
+	for i, helper := range s.helpers {
+		helper.us = s.us
+		helper.startTime = s.startTime
+		helper.nextTime = s.nextTime
+		helper.options = s.options
+		helper.quiescenceChecks = s.quiescenceChecks
+		helper.contempt = s.contempt
+		helper.selectivity = s.selectivity
+		helper.stats = pruningStats{}
+		helper.extensionStats = extensionStats{}
+		helper.ordering.newSearch()
+
+		atomic.StoreInt64(&helper.nodeCount, 0)
+		atomic.StoreInt64(&helper.qNodeCount, 0)
+
+		moves := &position.MoveList{Moves: append([]position.Move{}, legalMoves.Moves...)}
+
+		wg.Add(1)
+		go func(helper *AlphaBetaSearch, pos *position.Position, moves *position.MoveList, startDepth uint) {
+			defer wg.Done()
+			helper.iterativeDeepening(pos, moves, startDepth)
+		}(helper, pos.Copy(), moves, helperStartDepth(i))
+	}
+}
+
+// helperStartDepth returns the depth the helper with the given index starts iterative deepening at. Half of the helpers
+// start a ply deeper than the main thread, so that rather than every thread searching the same depth at the same time
+// they are spread over two, and the deeper ones fill the table with results the others will need next.
+func helperStartDepth(index int) uint {
+	return 1 + uint(index+1)%2
+}
+
+// nodes returns the number of nodes visited in the main search and in quiescence search, added up over every thread.
+func (s *AlphaBetaSearch) nodes() (int64, int64) {
+	nodes, qNodes := atomic.LoadInt64(&s.nodeCount), atomic.LoadInt64(&s.qNodeCount)
+
+	for _, helper := range s.helpers {
+		nodes += atomic.LoadInt64(&helper.nodeCount)
+		qNodes += atomic.LoadInt64(&helper.qNodeCount)
+	}
+
+	return nodes, qNodes
+}
+
+// totalNodes returns the number of nodes visited by every thread, including in quiescence search.
+func (s *AlphaBetaSearch) totalNodes() int64 {
+	nodes, qNodes := s.nodes()
+	return nodes + qNodes
+}
@@ -0,0 +1,140 @@
+package search
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ollybritton/StupidChess/position"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLazySMP tests that searching with helper threads still finds a forced mate, that the threads can be changed
+// between searches, and that the Threads option is checked.
+func TestLazySMP(t *testing.T) {
+	s := NewAlphaBetaSearch(make(chan Request), make(chan string), position.EvalSimple, position.EvalSimple)
+	go s.Root()
+	defer close(s.requests)
+
+	for _, threads := range []string{"4", "2", "1"} {
+		assert.NoError(t, s.SetOption("Threads", threads))
+
+		last, _ := searchToDepth(t, s, "7k/8/8/8/8/8/R7/1R4K1 w - - 0 1", 4)
+		assert.Contains(t, last, "score mate 2", "with %s threads", threads)
+	}
+
+	assert.Len(t, s.helpers, 0)
+	assert.Error(t, s.SetOption("Threads", "0"))
+	assert.Error(t, s.SetOption("Threads", "many"))
+}
+
+// TestLazySMPChangeWhileSearching tests that changing options or starting a new game while the threads are searching
+// stops the search and waits for it, rather than changing the table and helpers under it.
+func TestLazySMPChangeWhileSearching(t *testing.T) {
+	s := NewAlphaBetaSearch(make(chan Request), make(chan string), position.EvalSimple, position.EvalSimple)
+	go s.Root()
+	defer close(s.requests)
+
+	assert.NoError(t, s.SetOption("Threads", "3"))
+
+	changes := []func() error{
+		func() error { return s.SetOption("Hash", "2") },
+		func() error { return s.SetOption("Threads", "2") },
+		func() error { return s.SetOption("Clear Hash", "") },
+		func() error { s.NewGame(); return nil },
+	}
+
+	for _, change := range changes {
+		pos, err := position.NewPositionFromFEN("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+		assert.NoError(t, err)
+
+		bestMoves := make(chan string)
+		go func() {
+			for response := range s.responses {
+				if strings.HasPrefix(response, "bestmove") {
+					bestMoves <- response
+					return
+				}
+			}
+		}()
+
+		// With the default options the search would take an hour.
+		s.requests <- NewRequest(pos, NewDeafultOptions())
+		time.Sleep(50 * time.Millisecond)
+
+		assert.NoError(t, change())
+
+		select {
+		case bestMove := <-bestMoves:
+			assert.NotEqual(t, "bestmove 0000", bestMove)
+		case <-time.After(10 * time.Second):
+			t.Fatal("search wasn't stopped")
+		}
+	}
+
+	assert.Len(t, s.helpers, 1)
+}
+
+// TestStopBeforeSearchStarts tests that a search told to stop after it has been sent but before it has started, as
+// happens when an option is changed just after "go", stops as soon as it starts rather than running until it is told to
+// stop again.
+func TestStopBeforeSearchStarts(t *testing.T) {
+	s := NewAlphaBetaSearch(make(chan Request), make(chan string), position.EvalSimple, position.EvalSimple)
+	go s.Root()
+	defer close(s.requests)
+
+	pos, err := position.NewPositionFromFEN(position.StartingPosition)
+	assert.NoError(t, err)
+
+	bestMoves := make(chan string)
+	go func() {
+		for response := range s.responses {
+			if strings.HasPrefix(response, "bestmove") {
+				bestMoves <- response
+				return
+			}
+		}
+	}()
+
+	// Holding the lock keeps the search from starting, as if waitForSearch had been about to take it.
+	s.searching.Lock()
+	s.requests <- NewRequest(pos, NewDeafultOptions())
+	time.Sleep(50 * time.Millisecond)
+
+	s.Stop()
+	s.searching.Unlock()
+
+	select {
+	case bestMove := <-bestMoves:
+		assert.NotEqual(t, "bestmove 0000", bestMove)
+	case <-time.After(10 * time.Second):
+		s.Stop()
+		t.Fatal("stop was lost before the search started")
+	}
+}
+
+// TestTranspositionTableTorn tests that an entry made from the words of two different entries, as happens when two
+// threads store to the same slot at once, isn't returned for either position.
+func TestTranspositionTableTorn(t *testing.T) {
+	tt := NewTranspositionTable(MinHashSize)
+	first, second := uint64(1), uint64(1+tt.mask+1)
+
+	tt.Store(first, 5, 0, 3, BoundExact, position.NoMove)
+	entry := tt.entries[first&tt.mask]
+
+	tt.Store(second, 6, 0, -2, BoundLower, position.NoMove)
+	tt.entries[first&tt.mask].data = entry.data
+
+	_, ok := tt.Probe(first)
+	assert.False(t, ok)
+
+	_, ok = tt.Probe(second)
+	assert.False(t, ok)
+
+	tt.Store(first, 5, 0, 3, BoundExact, position.NoMove)
+	stored, ok := tt.Probe(first)
+	assert.True(t, ok)
+	assert.Equal(t, int16(3), stored.score)
+	assert.Equal(t, uint8(5), stored.depth)
+	assert.Equal(t, BoundExact, stored.bound)
+}
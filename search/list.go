@@ -6,10 +6,13 @@ import (
 	"github.com/ollybritton/StupidChess/position"
 )
 
+// maxPly is the maximum number of plies the search will look ahead from the root.
+const maxPly = 128
+
 type pvList []position.Move
 
 func (pv *pvList) new() {
-	*pv = make(pvList, 0, 100) // TODO: Replace 100 with max ply
+	*pv = make(pvList, 0, maxPly)
 }
 
 func (pv *pvList) add(mv position.Move) {
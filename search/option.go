@@ -0,0 +1,49 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OptionType is the type of an option that can be changed by the GUI, as described in the UCI protocol.
+type OptionType string
+
+const (
+	OptionCheck  OptionType = "check"  // OptionCheck is a boolean option, either "true" or "false".
+	OptionSpin   OptionType = "spin"   // OptionSpin is an integer option in a given range.
+	OptionCombo  OptionType = "combo"  // OptionCombo is an option that can be one of several predefined strings.
+	OptionButton OptionType = "button" // OptionButton is an option with no value that triggers an action.
+	OptionString OptionType = "string" // OptionString is an option that can be any string.
+)
+
+// Option describes an option that a searcher or engine exposes, which can be changed through the UCI "setoption"
+// command.
+type Option struct {
+	Name    string
+	Type    OptionType
+	Default string
+	Min     int      // Min is the smallest allowed value for spin options.
+	Max     int      // Max is the largest allowed value for spin options.
+	Vars    []string // Vars are the allowed values for combo options.
+}
+
+// String returns the option in the format used by the UCI "option" command, e.g.
+//
+//	option name Hash type spin default 16 min 1 max 4096
+func (o Option) String() string {
+	fields := []string{"option", "name", o.Name, "type", string(o.Type)}
+
+	if o.Type != OptionButton {
+		fields = append(fields, "default", o.Default)
+	}
+
+	if o.Type == OptionSpin {
+		fields = append(fields, "min", fmt.Sprint(o.Min), "max", fmt.Sprint(o.Max))
+	}
+
+	for _, v := range o.Vars {
+		fields = append(fields, "var", v)
+	}
+
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,169 @@
+package search
+
+import "github.com/ollybritton/StupidChess/position"
+
+// Good move ordering makes alpha-beta search much faster, since the sooner the best move is searched, the sooner the
+// other moves can be cut off. Captures are ordered by static exchange evaluation and MVV-LVA, but there's no cheap way
+// to tell how good a quiet move is by looking at it, so instead the search remembers which quiet moves caused cutoffs:
+//
+//   - killer moves are the last two quiet moves to cause a cutoff at the same ply, in any position
+//   - the countermove is the quiet move that last caused a cutoff in reply to the same previous move
+//   - the history table scores quiet moves by their start and end squares, going up when they cause a cutoff and down
+//     when another move does
+//   - continuation history does the same, but separately for each of the previous two moves, so it learns which moves
+//     are good replies to or follow-ups of which
+//
+// For more information: https://www.chessprogramming.org/Move_Ordering
+
+// maxHistory is the largest score a history entry can reach. History scores are updated with "gravity", which makes
+// each update smaller the closer the entry already is to the limit in that direction, so entries never overflow and
+// recent results count for more than old ones.
+const maxHistory = 16384
+
+// moveOrdering holds everything the search has learned about which quiet moves are good. It belongs to a single
+// search, and between "go" commands the killers are cleared and the history is aged, since the positions searched next
+// time will be different.
+type moveOrdering struct {
+	killers      [maxPly][2]position.Move // killers are the last two quiet moves to cause a beta cutoff at each ply.
+	counterMoves [12][64]position.Move    // counterMoves are indexed by the piece and destination of the previous move.
+	history      [2][64][64]int16         // history is indexed by the side to move and the start and end squares of a move.
+
+	// continuation is indexed by the piece and destination of an earlier move and then by the piece and destination
+	// of a move in reply to it.
+	continuation [12][64][12][64]int16
+
+	stack [maxPly + 1]position.Move // stack holds the move made at each ply of the line being searched, or NoMove for a null move.
+}
+
+// clear forgets everything, e.g. when starting a new game.
+func (o *moveOrdering) clear() {
+	*o = moveOrdering{}
+}
+
+// newSearch prepares for searching a new position. Killers are specific to the plies of the last search so they are
+// cleared, while history is still useful and is only halved so that it can adapt to the new position.
+func (o *moveOrdering) newSearch() {
+	o.killers = [maxPly][2]position.Move{}
+	o.stack = [maxPly + 1]position.Move{}
+
+	for side := range o.history {
+		for from := range o.history[side] {
+			for to := range o.history[side][from] {
+				o.history[side][from][to] /= 2
+			}
+		}
+	}
+
+	for piece := range o.continuation {
+		for square := range o.continuation[piece] {
+			for reply := range o.continuation[piece][square] {
+				for to := range o.continuation[piece][square][reply] {
+					o.continuation[piece][square][reply][to] /= 2
+				}
+			}
+		}
+	}
+}
+
+// setMove records the move made at the given ply, so that moves at later plies can be ordered by what came before.
+func (o *moveOrdering) setMove(ply int, move position.Move) {
+	if ply <= maxPly {
+		o.stack[ply] = move
+	}
+}
+
+// previous returns the move made the given number of plies before the given ply, or NoMove if there wasn't one.
+func (o *moveOrdering) previous(ply, plies int) position.Move {
+	if ply-plies < 0 || ply-plies > maxPly {
+		return position.NoMove
+	}
+
+	return o.stack[ply-plies]
+}
+
+// killersAt returns the killer moves at the given ply.
+func (o *moveOrdering) killersAt(ply int) [2]position.Move {
+	if ply >= maxPly {
+		return [2]position.Move{}
+	}
+
+	return o.killers[ply]
+}
+
+// counterMove returns the quiet move that last caused a cutoff in reply to the move before the given ply.
+func (o *moveOrdering) counterMove(ply int) position.Move {
+	previous := o.previous(ply, 1)
+	if previous == position.NoMove {
+		return position.NoMove
+	}
+
+	return o.counterMoves[previous.Moved()][previous.To()]
+}
+
+// quietScore returns how good a quiet move made at the given ply is likely to be, from the history tables.
+func (o *moveOrdering) quietScore(side position.Color, move position.Move, ply int) int {
+	score := int(o.history[side][move.From()][move.To()])
+
+	for _, plies := range []int{1, 2} {
+		if previous := o.previous(ply, plies); previous != position.NoMove {
+			score += int(o.continuation[previous.Moved()][previous.To()][move.Moved()][move.To()])
+		}
+	}
+
+	return score
+}
+
+// update learns from a quiet move that caused a beta cutoff at the given ply. It becomes a killer and the countermove
+// for the previous move, its history scores go up, and the history scores of the quiet moves tried before it go down.
+func (o *moveOrdering) update(side position.Color, move position.Move, quietsTried []position.Move, depth uint, ply int) {
+	if ply < maxPly && !o.killers[ply][0].Equal(move) {
+		o.killers[ply][1] = o.killers[ply][0]
+		o.killers[ply][0] = move
+	}
+
+	if previous := o.previous(ply, 1); previous != position.NoMove {
+		o.counterMoves[previous.Moved()][previous.To()] = move
+	}
+
+	bonus := historyBonus(depth)
+
+	o.updateHistory(side, move, ply, bonus)
+
+	for _, tried := range quietsTried {
+		if !tried.Equal(move) {
+			o.updateHistory(side, tried, ply, -bonus)
+		}
+	}
+}
+
+// updateHistory adds a bonus, which may be negative, to the history scores of a move.
+func (o *moveOrdering) updateHistory(side position.Color, move position.Move, ply int, bonus int) {
+	applyGravity(&o.history[side][move.From()][move.To()], bonus)
+
+	for _, plies := range []int{1, 2} {
+		if previous := o.previous(ply, plies); previous != position.NoMove {
+			applyGravity(&o.continuation[previous.Moved()][previous.To()][move.Moved()][move.To()], bonus)
+		}
+	}
+}
+
+// historyBonus returns how much a cutoff at the given depth changes history scores by. Cutoffs found by deeper
+// searches are more reliable, so they count for more.
+func historyBonus(depth uint) int {
+	if depth > 16 {
+		depth = 16
+	}
+
+	return int(depth*depth) * 32
+}
+
+// applyGravity adds a bonus to a history entry, scaled down by how close the entry already is to maxHistory in the
+// same direction.
+func applyGravity(entry *int16, bonus int) {
+	magnitude := bonus
+	if magnitude < 0 {
+		magnitude = -magnitude
+	}
+
+	*entry += int16(bonus - int(*entry)*magnitude/maxHistory)
+}
@@ -0,0 +1,44 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/ollybritton/StupidChess/position"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMoveOrdering tests that a quiet move causing a cutoff becomes a killer and the countermove, that its history goes
+// up while the moves tried before it go down, that history never passes maxHistory, and that a new search clears the
+// killers and halves the history.
+func TestMoveOrdering(t *testing.T) {
+	pos, err := position.NewPositionFromFEN(position.StartingPosition)
+	assert.NoError(t, err)
+
+	moves := pos.MovesQuiet().AsSlice()
+	previous, good, bad := moves[0], moves[1], moves[2]
+
+	var o moveOrdering
+	o.setMove(0, previous)
+
+	for i := 0; i < 100; i++ {
+		o.update(position.White, good, []position.Move{bad, good}, 10, 1)
+	}
+
+	assert.Equal(t, [2]position.Move{good, position.NoMove}, o.killersAt(1))
+	assert.Equal(t, good, o.counterMove(1))
+
+	goodScore := o.quietScore(position.White, good, 1)
+	badScore := o.quietScore(position.White, bad, 1)
+	assert.Greater(t, goodScore, 0)
+	assert.Less(t, badScore, 0)
+	assert.LessOrEqual(t, int(o.history[position.White][good.From()][good.To()]), maxHistory)
+	assert.GreaterOrEqual(t, int(o.history[position.White][bad.From()][bad.To()]), -maxHistory)
+
+	o.newSearch()
+	o.setMove(0, previous)
+
+	assert.Equal(t, [2]position.Move{}, o.killersAt(1))
+	assert.Equal(t, good, o.counterMove(1))
+	assert.InDelta(t, goodScore/2, o.quietScore(position.White, good, 1), 1)
+	assert.InDelta(t, badScore/2, o.quietScore(position.White, bad, 1), 1)
+}
@@ -0,0 +1,273 @@
+package search
+
+import "github.com/ollybritton/StupidChess/position"
+
+// pickerStage is the stage a movePicker is at. Moves are generated one stage at a time so that if an early move causes
+// a cutoff, the later moves never have to be generated at all.
+type pickerStage uint8
+
+const (
+	stageHashMove         pickerStage = iota // stageHashMove tries the move from the transposition table.
+	stageGenerateCaptures                    // stageGenerateCaptures generates and orders captures and promotions.
+	stageGoodCaptures                        // stageGoodCaptures tries captures that don't lose material.
+	stageRefutations                         // stageRefutations tries the killer moves and the countermove.
+	stageGenerateQuiets                      // stageGenerateQuiets generates quiet moves and scores them by history.
+	stageQuiets                              // stageQuiets tries the quiet moves, highest history score first.
+	stageBadCaptures                         // stageBadCaptures tries captures that lose material.
+	stageGenerateEvasions                    // stageGenerateEvasions generates and orders every move out of check.
+	stageEvasions                            // stageEvasions tries the moves out of check.
+	stageDone                                // stageDone means there are no moves left.
+)
+
+// movePicker returns the legal moves in a position one at a time, in roughly best-first order:
+//   - the hash move
+//   - captures that don't lose material according to static exchange evaluation, most valuable victim first
+//   - killer moves and the countermove
+//   - quiet moves, ordered by their history scores
+//   - captures that lose material
+//
+// When in check every evasion is generated at once instead, with captures first.
+//
+// In quiescence search there is no hash move and no move ordering state, captures that lose material are skipped, and
+// quiet moves are only returned if checks is set.
+type movePicker struct {
+	pos   *position.Position
+	stage pickerStage
+
+	hashMove    position.Move    // hashMove is the move from the transposition table, or NoMove if there isn't one.
+	refutations [3]position.Move // refutations are the two killer moves followed by the countermove.
+	ordering    *moveOrdering    // ordering scores quiet moves, and is nil in quiescence search.
+	ply         int              // ply is the distance from the root, which the killers and history depend on.
+
+	inCheck    bool // inCheck is true if the side to move is in check, so only evasions are generated.
+	quiescence bool // quiescence is true if the picker is being used by quiescence search.
+	checks     bool // checks is true if quiet moves should be returned in quiescence search.
+
+	moves       *position.MoveList // moves are the moves generated for the current stage.
+	scores      []int              // scores are the history scores of the quiet moves, in the same order as moves.
+	badCaptures []position.Move    // badCaptures are the captures put off until after the quiet moves.
+	index       int                // index is the position of the next move to try in moves or badCaptures.
+}
+
+// newMovePicker returns a move picker for the main search at the given ply.
+func newMovePicker(pos *position.Position, hashMove position.Move, ordering *moveOrdering, ply int, inCheck bool) *movePicker {
+	killers := ordering.killersAt(ply)
+
+	return &movePicker{
+		pos:         pos,
+		stage:       stageHashMove,
+		hashMove:    hashMove,
+		refutations: [3]position.Move{killers[0], killers[1], ordering.counterMove(ply)},
+		ordering:    ordering,
+		ply:         ply,
+		inCheck:     inCheck,
+	}
+}
+
+// newQuiescencePicker returns a move picker for quiescence search, which returns captures and promotions, or every
+// evasion when in check. If checks is true, quiet moves are returned after the captures so that checks can be searched.
+func newQuiescencePicker(pos *position.Position, inCheck, checks bool) *movePicker {
+	picker := &movePicker{
+		pos:        pos,
+		stage:      stageGenerateCaptures,
+		inCheck:    inCheck,
+		quiescence: true,
+		checks:     checks,
+	}
+
+	if inCheck {
+		picker.stage = stageGenerateEvasions
+	}
+
+	return picker
+}
+
+// next returns the next move to try, or false if there are no moves left.
+func (mp *movePicker) next() (position.Move, bool) {
+	for {
+		switch mp.stage {
+		case stageHashMove:
+			mp.stage = stageGenerateCaptures
+			if mp.inCheck {
+				mp.stage = stageGenerateEvasions
+			}
+
+			if mp.hashMove != position.NoMove && mp.pos.IsLegal(mp.hashMove) {
+				return mp.hashMove, true
+			}
+
+			mp.hashMove = position.NoMove
+
+		case stageGenerateCaptures:
+			mp.moves = mp.pos.MovesCaptures()
+			orderCaptures(mp.moves)
+
+			mp.index = 0
+			mp.stage = stageGoodCaptures
+
+		case stageGoodCaptures:
+			for mp.index < mp.moves.Len() {
+				move := mp.moves.Moves[mp.index]
+				mp.index++
+
+				if move.Equal(mp.hashMove) {
+					continue
+				}
+
+				if !mp.pos.SEEAtLeast(move, 0) {
+					mp.badCaptures = append(mp.badCaptures, move)
+					continue
+				}
+
+				return move, true
+			}
+
+			mp.index = 0
+			mp.stage = stageRefutations
+
+			// Captures that lose material are very unlikely to help in quiescence search, so they are skipped.
+			if mp.quiescence {
+				mp.stage = stageDone
+			}
+
+			// In quiescence search the quiet moves come last, since most of them won't give check.
+			if mp.quiescence && mp.checks {
+				mp.stage = stageGenerateQuiets
+			}
+
+		case stageRefutations:
+			for mp.index < len(mp.refutations) {
+				move := mp.refutations[mp.index]
+				mp.index++
+
+				if move == position.NoMove {
+					continue
+				}
+
+				move = rebuildMove(mp.pos, move)
+				mp.refutations[mp.index-1] = move
+
+				// Moves that can't be played here, or that have already been tried, are cleared so that they aren't
+				// skipped again when the quiet moves are tried.
+				if move.Equal(mp.hashMove) || isNoisy(move) || mp.isRefutation(move, mp.index-1) ||
+					!mp.pos.IsLegal(move) {
+					mp.refutations[mp.index-1] = position.NoMove
+					continue
+				}
+
+				return move, true
+			}
+
+			mp.stage = stageGenerateQuiets
+
+		case stageGenerateQuiets:
+			mp.moves = mp.pos.MovesQuiet()
+			mp.scores = mp.scores[:0]
+
+			if mp.ordering != nil {
+				for _, move := range mp.moves.Moves {
+					mp.scores = append(mp.scores, mp.ordering.quietScore(mp.pos.SideToMove, move, mp.ply))
+				}
+			}
+
+			mp.index = 0
+			mp.stage = stageQuiets
+
+		case stageQuiets:
+			for mp.index < mp.moves.Len() {
+				mp.selectBestQuiet()
+
+				move := mp.moves.Moves[mp.index]
+				mp.index++
+
+				if move.Equal(mp.hashMove) || mp.isRefutation(move, len(mp.refutations)) {
+					continue
+				}
+
+				return move, true
+			}
+
+			mp.index = 0
+			mp.stage = stageBadCaptures
+
+			if mp.quiescence {
+				mp.stage = stageDone
+			}
+
+		case stageBadCaptures:
+			if mp.index < len(mp.badCaptures) {
+				mp.index++
+				return mp.badCaptures[mp.index-1], true
+			}
+
+			mp.index = 0
+			mp.stage = stageDone
+
+		case stageGenerateEvasions:
+			mp.moves = mp.pos.MovesEvasions()
+			orderCaptures(mp.moves)
+
+			mp.index = 0
+			mp.stage = stageEvasions
+
+		case stageEvasions:
+			for mp.index < mp.moves.Len() {
+				move := mp.moves.Moves[mp.index]
+				mp.index++
+
+				if move.Equal(mp.hashMove) {
+					continue
+				}
+
+				return move, true
+			}
+
+			mp.stage = stageDone
+
+		case stageDone:
+			return position.NoMove, false
+		}
+	}
+}
+
+// rebuildMove returns the move with the same start and end squares and promotion as the given move, but as it would be
+// made in the position. Killers and countermoves are found in other positions, which may have had different castling
+// rights or en passant targets, and IsLegal won't accept a move that doesn't match the position exactly.
+func rebuildMove(pos *position.Position, move position.Move) position.Move {
+	from, to := move.From(), move.To()
+
+	if move.IsCastle() {
+		return position.NewCastlingMove(from, to, pos.Squares[from], pos.Castling, pos.EnPassant)
+	}
+
+	return position.NewMove(from, to, pos.Squares[from], pos.Squares[to], move.Promotion(), pos.Castling, pos.EnPassant)
+}
+
+// isRefutation returns true if the move is one of the first n refutations.
+func (mp *movePicker) isRefutation(move position.Move, n int) bool {
+	for _, refutation := range mp.refutations[:n] {
+		if refutation != position.NoMove && move.Equal(refutation) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// selectBestQuiet swaps the quiet move with the highest history score into the next position to be tried. This is a
+// step of a selection sort, which is done one move at a time since a cutoff often means most moves are never tried.
+func (mp *movePicker) selectBestQuiet() {
+	if len(mp.scores) == 0 {
+		return
+	}
+
+	best := mp.index
+	for i := mp.index + 1; i < len(mp.scores); i++ {
+		if mp.scores[i] > mp.scores[best] {
+			best = i
+		}
+	}
+
+	mp.moves.Moves[mp.index], mp.moves.Moves[best] = mp.moves.Moves[best], mp.moves.Moves[mp.index]
+	mp.scores[mp.index], mp.scores[best] = mp.scores[best], mp.scores[mp.index]
+}
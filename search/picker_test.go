@@ -0,0 +1,109 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/ollybritton/StupidChess/position"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMovePicker tests that the move picker returns every legal move exactly once, whatever the hash move, killers,
+// countermove and history are, and that the hash move comes first.
+func TestMovePicker(t *testing.T) {
+	fens := []string{
+		position.StartingPosition,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		"4k3/8/8/b7/8/8/3R4/r3K2N w - - 0 1",
+	}
+
+	for _, fen := range fens {
+		pos, err := position.NewPositionFromFEN(fen)
+		assert.NoError(t, err)
+
+		legal := pos.MovesLegal().AsSlice()
+		inCheck := pos.KingInCheck(pos.SideToMove)
+
+		// A move from another position should never be returned.
+		other, err := position.NewPositionFromFEN("8/8/8/4k3/8/8/3K4/8 w - - 0 1")
+		assert.NoError(t, err)
+		bogus := other.MovesLegal().Moves[0]
+
+		for _, hashMove := range append([]position.Move{position.NoMove, bogus}, legal...) {
+			// The countermove duplicates a killer, and some quiet moves have history scores.
+			ordering := &moveOrdering{}
+			ordering.killers[2] = [2]position.Move{bogus, legal[len(legal)-1]}
+			ordering.setMove(1, bogus)
+			ordering.counterMoves[bogus.Moved()][bogus.To()] = legal[len(legal)-1]
+			for i, move := range legal {
+				ordering.updateHistory(pos.SideToMove, move, 2, i%5*100)
+			}
+
+			picker := newMovePicker(pos, hashMove, ordering, 2, inCheck)
+
+			seen := make(map[position.Move]int)
+			first := true
+
+			for {
+				move, ok := picker.next()
+				if !ok {
+					break
+				}
+
+				if first && hashMove != bogus && hashMove != position.NoMove {
+					assert.True(t, move.Equal(hashMove), "hash move %s not first in %s", hashMove, fen)
+				}
+
+				// Captures are returned with the score used to order them, which the legal moves don't have.
+				first = false
+				move.SetEval(0)
+				seen[move]++
+			}
+
+			assert.Len(t, seen, len(legal), "moves in %s with hash move %s", fen, hashMove)
+
+			for _, move := range legal {
+				assert.Equal(t, 1, seen[move], "move %s in %s with hash move %s", move, fen, hashMove)
+			}
+		}
+	}
+}
+
+// TestMovePickerRefutationState tests that a killer found in a position with different castling rights and en passant
+// target is still tried as a killer, as the move it would be in the current position.
+func TestMovePickerRefutationState(t *testing.T) {
+	pos, err := position.NewPositionFromFEN("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1")
+	assert.NoError(t, err)
+
+	other, err := position.NewPositionFromFEN("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b kq - 0 1")
+	assert.NoError(t, err)
+
+	killer, err := other.ParseMove("g8f6")
+	assert.NoError(t, err)
+
+	want, err := pos.ParseMove("g8f6")
+	assert.NoError(t, err)
+	assert.False(t, killer.Equal(want), "killer should differ from the move in this position")
+
+	ordering := &moveOrdering{}
+	ordering.killers[1] = [2]position.Move{killer, position.NoMove}
+
+	picker := newMovePicker(pos, position.NoMove, ordering, 1, false)
+
+	first, ok := picker.next()
+	assert.True(t, ok)
+	assert.True(t, first.Equal(want), "first move was %s", first)
+
+	count := 1
+	for {
+		move, ok := picker.next()
+		if !ok {
+			break
+		}
+
+		assert.False(t, move.Equal(want), "killer returned twice")
+		count++
+	}
+
+	assert.Equal(t, pos.MovesLegal().Len(), count)
+}
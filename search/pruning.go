@@ -0,0 +1,138 @@
+package search
+
+import (
+	"fmt"
+
+	"github.com/ollybritton/StupidChess/position"
+)
+
+// The search doesn't look at every move to the full depth. These are the techniques it uses to decide which moves and
+// positions it can skip or search less deeply, and the extensions in extensions.go decide which it should search more
+// deeply. Each one can be turned off with a UCI option so that its effect on playing strength can be measured.
+//
+// For more information: https://www.chessprogramming.org/Selectivity
+
+// selectivity holds which pruning, reduction and extension techniques are enabled.
+type selectivity struct {
+	nullMove        bool // nullMove enables null move pruning.
+	lateMoves       bool // lateMoves enables late move reductions.
+	futility        bool // futility enables futility pruning of quiet moves near the leaves.
+	reverseFutility bool // reverseFutility enables reverse futility pruning, also called static null move pruning.
+	razoring        bool // razoring enables dropping into quiescence search in positions far below alpha.
+	mateDistance    bool // mateDistance enables mate distance pruning.
+
+	checkExtension      bool // checkExtension enables extending moves that give check.
+	passedPawnExtension bool // passedPawnExtension enables extending pawn pushes to the seventh rank.
+	recaptureExtension  bool // recaptureExtension enables extending recaptures on the principal variation.
+	singularExtension   bool // singularExtension enables extending hash moves that are much better than the others.
+}
+
+// defaultSelectivity has every technique enabled.
+var defaultSelectivity = selectivity{
+	nullMove:        true,
+	lateMoves:       true,
+	futility:        true,
+	reverseFutility: true,
+	razoring:        true,
+	mateDistance:    true,
+
+	checkExtension:      true,
+	passedPawnExtension: true,
+	recaptureExtension:  true,
+	singularExtension:   true,
+}
+
+// selectivityOptions are the UCI options for turning each technique on or off, and the switch each one controls.
+var selectivityOptions = []struct {
+	name    string
+	enabled func(*selectivity) *bool
+}{
+	{"NullMovePruning", func(s *selectivity) *bool { return &s.nullMove }},
+	{"LateMoveReductions", func(s *selectivity) *bool { return &s.lateMoves }},
+	{"FutilityPruning", func(s *selectivity) *bool { return &s.futility }},
+	{"ReverseFutilityPruning", func(s *selectivity) *bool { return &s.reverseFutility }},
+	{"Razoring", func(s *selectivity) *bool { return &s.razoring }},
+	{"MateDistancePruning", func(s *selectivity) *bool { return &s.mateDistance }},
+	{"CheckExtensions", func(s *selectivity) *bool { return &s.checkExtension }},
+	{"PassedPawnExtensions", func(s *selectivity) *bool { return &s.passedPawnExtension }},
+	{"RecaptureExtensions", func(s *selectivity) *bool { return &s.recaptureExtension }},
+	{"SingularExtensions", func(s *selectivity) *bool { return &s.singularExtension }},
+}
+
+// Margins and limits for the techniques, in plies of depth and the same units as the evaluation.
+const (
+	nullMoveMinDepth        = 3 // nullMoveMinDepth is the least depth at which a null move is tried.
+	nullMoveReduction       = 2 // nullMoveReduction is how much less deeply the position after a null move is searched.
+	nullMoveVerifyDepth     = 6 // nullMoveVerifyDepth is the least depth at which a null move cutoff is checked by a search without one.
+	lateMoveMinDepth        = 3 // lateMoveMinDepth is the least depth at which late moves are reduced.
+	lateMoveMinCount        = 4 // lateMoveMinCount is the number of the first move that can be reduced.
+	futilityMaxDepth        = 2 // futilityMaxDepth is the greatest depth at which quiet moves are pruned by futility pruning.
+	futilityMargin          = 2 // futilityMargin is how far below alpha per ply the static evaluation must be to prune quiet moves.
+	reverseFutilityMaxDepth = 3 // reverseFutilityMaxDepth is the greatest depth at which reverse futility pruning is used.
+	reverseFutilityMargin   = 1 // reverseFutilityMargin is how far above beta per ply the static evaluation must be to prune.
+	razoringMaxDepth        = 2 // razoringMaxDepth is the greatest depth at which razoring is used.
+	razoringMargin          = 3 // razoringMargin is how far below alpha per ply the static evaluation must be to razor.
+)
+
+// pruningStats counts how often each technique was used in a search.
+type pruningStats struct {
+	nullMoveTries       int // nullMoveTries is the number of null moves searched.
+	nullMoveCutoffs     int // nullMoveCutoffs is the number of null moves that caused a cutoff.
+	lateMoveReductions  int // lateMoveReductions is the number of moves searched at a reduced depth.
+	lateMoveResearches  int // lateMoveResearches is the number of reduced moves that had to be searched again.
+	futilityPrunes      int // futilityPrunes is the number of quiet moves skipped by futility pruning.
+	reverseFutilityCuts int // reverseFutilityCuts is the number of positions cut off by reverse futility pruning.
+	razoringCuts        int // razoringCuts is the number of positions cut off by razoring.
+	mateDistanceCuts    int // mateDistanceCuts is the number of positions cut off by mate distance pruning.
+}
+
+// String returns the statistics as they are sent in a UCI "info string" command.
+func (p pruningStats) String() string {
+	return fmt.Sprintf(
+		"nullmove %d/%d lmr %d researched %d futility %d reversefutility %d razoring %d matedistance %d",
+		p.nullMoveCutoffs,
+		p.nullMoveTries,
+		p.lateMoveReductions,
+		p.lateMoveResearches,
+		p.futilityPrunes,
+		p.reverseFutilityCuts,
+		p.razoringCuts,
+		p.mateDistanceCuts,
+	)
+}
+
+// add adds the statistics from another thread's search.
+func (p *pruningStats) add(other pruningStats) {
+	p.nullMoveTries += other.nullMoveTries
+	p.nullMoveCutoffs += other.nullMoveCutoffs
+	p.lateMoveReductions += other.lateMoveReductions
+	p.lateMoveResearches += other.lateMoveResearches
+	p.futilityPrunes += other.futilityPrunes
+	p.reverseFutilityCuts += other.reverseFutilityCuts
+	p.razoringCuts += other.razoringCuts
+	p.mateDistanceCuts += other.mateDistanceCuts
+}
+
+// hasNonPawnMaterial returns true if the side to move has any pieces other than pawns and its king. Without them,
+// zugzwang is common enough that passing would often be the best move if it were allowed, so null move pruning can't
+// be trusted.
+func hasNonPawnMaterial(pos *position.Position) bool {
+	pieces := pos.Pieces[position.Knight] | pos.Pieces[position.Bishop] | pos.Pieces[position.Rook] | pos.Pieces[position.Queen]
+	return pieces&pos.Occupied[pos.SideToMove] != 0
+}
+
+// lateMoveReduction returns how many plies less deeply to search a quiet move which is the given number in the move
+// ordering. Moves further down the list are less likely to be any good, so they are reduced more.
+func lateMoveReduction(depth uint, moveCount int, pvNode bool) uint {
+	reduction := uint(1)
+	if moveCount > 2*lateMoveMinCount && !pvNode {
+		reduction = 2
+	}
+
+	// The reduced search should still be at least one ply deep.
+	if reduction > depth-2 {
+		reduction = depth - 2
+	}
+
+	return reduction
+}
@@ -0,0 +1,123 @@
+package search
+
+import (
+	"sync/atomic"
+
+	"github.com/ollybritton/StupidChess/position"
+)
+
+// quiescence continues the search past the depth limit until the position is "quiet", i.e. there are no more captures
+// or promotions to be made. Without this the search would suffer from the horizon effect, e.g. it would happily stop
+// searching after taking a queen with a queen without seeing that the queen is then recaptured.
+//
+// The side to move is allowed to "stand pat" and take the static evaluation instead of making a capture, since in a
+// real game they aren't forced to capture anything. This is only unsound when in check, in which case every evasion is
+// searched instead. Captures that lose material according to static exchange evaluation are skipped, since standing pat
+// is almost always better.
+//
+// When quiescence checks are enabled, quiet moves that give check are also searched at the first ply of quiescence.
+//
+// For more information: https://www.chessprogramming.org/Quiescence_Search
+func (s *AlphaBetaSearch) quiescence(alpha int16, beta int16, qply int, ply int, pos *position.Position) int16 {
+	atomic.AddInt64(&s.qNodeCount, 1)
+
+	if ply > s.selDepth {
+		s.selDepth = ply
+	}
+
+	if ply >= maxPly {
+		return s.evaluate(pos)
+	}
+
+	inCheck := pos.KingInCheck(pos.SideToMove)
+	bestScore := position.NoEval
+
+	if !inCheck {
+		standPat := s.evaluate(pos)
+
+		if standPat >= beta {
+			return standPat
+		}
+
+		if standPat > alpha {
+			alpha = standPat
+		}
+
+		bestScore = standPat
+	}
+
+	searchChecks := s.quiescenceChecks && qply == 0
+
+	picker := newQuiescencePicker(pos, inCheck, searchChecks)
+
+	legalMoves := 0
+
+	for {
+		move, ok := picker.next()
+		if !ok {
+			break
+		}
+
+		pos.MakeMove(move)
+		legalMoves++
+
+		// The picker only returns quiet moves when we are in check or checks are enabled, and in the second case only
+		// the ones that give check are searched.
+		if !inCheck && !isNoisy(move) && !pos.KingInCheck(pos.SideToMove) {
+			pos.UndoMove(move)
+			continue
+		}
+
+		score := -s.quiescence(-beta, -alpha, qply+1, ply+1, pos)
+		pos.UndoMove(move)
+
+		if s.stopped() {
+			return alpha
+		}
+
+		if score > bestScore {
+			bestScore = score
+		}
+
+		if score > alpha {
+			alpha = score
+		}
+
+		if alpha >= beta {
+			break
+		}
+	}
+
+	// If we're in check every move was searched, so having no legal moves means we've been checkmated.
+	if inCheck && legalMoves == 0 {
+		return matedScore(ply)
+	}
+
+	return bestScore
+}
+
+// isNoisy returns true if a move is a capture or a promotion, which are the moves searched by quiescence.
+func isNoisy(move position.Move) bool {
+	return move.IsCapture() || move.IsPromotion()
+}
+
+// orderCaptures sorts moves so that captures of valuable pieces by less valuable pieces come first (MVV-LVA, "most
+// valuable victim, least valuable attacker"), followed by promotions and then quiet moves.
+func orderCaptures(moves *position.MoveList) {
+	for i, move := range moves.Moves {
+		score := int16(0)
+
+		if move.IsCapture() {
+			score += 8*int16(move.Captured().Colorless()+1) - int16(move.Moved().Colorless())
+		}
+
+		if move.IsPromotion() {
+			score += int16(move.Promotion())
+		}
+
+		move.SetEval(score)
+		moves.Moves[i] = move
+	}
+
+	moves.Sort()
+}
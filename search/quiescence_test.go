@@ -0,0 +1,67 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ollybritton/StupidChess/position"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestQuiescenceRecapture tests that a one ply search doesn't stop in the middle of an exchange: taking the rook on d5
+// with the queen looks best until the pawn recaptures, so the search should take the undefended knight instead.
+func TestQuiescenceRecapture(t *testing.T) {
+	s := NewAlphaBetaSearch(make(chan Request), make(chan string), position.EvalSimple, position.EvalSimple)
+	go s.Root()
+	defer close(s.requests)
+
+	last, bestMove := searchToDepth(t, s, "4k3/8/2p5/3r4/n7/8/8/3QK3 w - - 0 1", 1)
+	assert.Contains(t, last, "score cp 300")
+	assert.Equal(t, "d1a4", bestMove)
+}
+
+// TestQuiescenceReporting tests that the nodes visited by quiescence search are counted separately, and that the
+// selective depth includes the plies it searched past the depth limit.
+func TestQuiescenceReporting(t *testing.T) {
+	s := NewAlphaBetaSearch(make(chan Request), make(chan string), position.EvalSimple, position.EvalSimple)
+	go s.Root()
+	defer close(s.requests)
+
+	var nodes, qNodes, threads, selDepth int
+
+	for _, response := range searchResponses(t, s, "4k3/8/2p5/3r4/n7/8/8/3QK3 w - - 0 1", 1) {
+		if strings.HasPrefix(response, "info string nodes") {
+			_, err := fmt.Sscanf(response, "info string nodes %d qnodes %d threads %d", &nodes, &qNodes, &threads)
+			assert.NoError(t, err)
+		}
+
+		if strings.HasPrefix(response, "info depth 1 ") {
+			_, err := fmt.Sscanf(response, "info depth 1 seldepth %d", &selDepth)
+			assert.NoError(t, err)
+		}
+	}
+
+	assert.Greater(t, qNodes, 0)
+	assert.Greater(t, selDepth, 1, "quiescence plies not included in seldepth")
+}
+
+// TestQuiescenceChecks tests that the QuiescenceChecks option lets a one ply search see a quiet mate in reply. Taking
+// the knight on b7 with the queen stops the queen guarding e1, which allows Re1#.
+func TestQuiescenceChecks(t *testing.T) {
+	s := NewAlphaBetaSearch(make(chan Request), make(chan string), position.EvalSimple, position.EvalSimple)
+	go s.Root()
+	defer close(s.requests)
+
+	fen := "4r1k1/1n3ppp/8/8/1Q6/8/5PPP/6K1 w - - 0 1"
+
+	last, bestMove := searchToDepth(t, s, fen, 1)
+	assert.Contains(t, last, "score cp 400")
+	assert.Equal(t, "b4b7", bestMove)
+
+	assert.NoError(t, s.SetOption("QuiescenceChecks", "true"))
+
+	last, bestMove = searchToDepth(t, s, fen, 1)
+	assert.Contains(t, last, "score cp 100")
+	assert.NotEqual(t, "b4b7", bestMove)
+}
@@ -5,4 +5,8 @@ type Searcher interface {
 	Responses() chan string
 	Root() error
 	Stop()
+
+	NewGame()
+	Options() []Option
+	SetOption(name, value string) error
 }
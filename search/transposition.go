@@ -0,0 +1,221 @@
+package search
+
+import (
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/ollybritton/StupidChess/position"
+)
+
+const (
+	DefaultHashSize = 16    // DefaultHashSize is the default size of the transposition table in megabytes.
+	MinHashSize     = 1     // MinHashSize is the smallest size the transposition table can be set to in megabytes.
+	MaxHashSize     = 4_096 // MaxHashSize is the largest size the transposition table can be set to in megabytes.
+)
+
+// Bound describes how a score stored in the transposition table relates to the true score of the position.
+type Bound uint8
+
+const (
+	BoundNone  Bound = iota // BoundNone means the entry is empty.
+	BoundExact              // BoundExact means the score is the exact score of the position.
+	BoundLower              // BoundLower means the search failed high, so the true score is at least the stored score.
+	BoundUpper              // BoundUpper means the search failed low, so the true score is at most the stored score.
+)
+
+// ttEntry is a single entry in the transposition table.
+type ttEntry struct {
+	key   uint64        // key is the full Zobrist hash of the position, used to detect index collisions.
+	move  position.Move // move is the best move found in the position, or position.NoMove if none was found.
+	score int16         // score is the score of the position, adjusted so that mate scores are relative to this node.
+	depth uint8         // depth is the depth the position was searched to.
+	bound Bound         // bound says whether score is exact, a lower bound or an upper bound.
+	age   uint8         // age is the generation of the search that stored the entry.
+}
+
+// ttSlot is how an entry is kept in the table. Several threads can read and write the same slot at once, so each word
+// is loaded and stored atomically, and the key is stored XORed with the other two words. If two threads write to a slot
+// at the same time and it ends up with words from both, the key no longer matches either position and the entry is
+// ignored, rather than giving one position the move or score of another.
+//
+// For more information: https://www.chessprogramming.org/Shared_Hash_Table#Lock-less
+type ttSlot struct {
+	check uint64 // check is the key XORed with move and data.
+	move  uint64 // move is the best move found in the position.
+	data  uint64 // data holds the score, depth, bound and age of the entry.
+}
+
+// TranspositionTable is a fixed-size hash table that stores the results of searching positions so that they don't have
+// to be searched again when they are reached through a different move order, or on the next iteration of iterative
+// deepening. It is safe to probe and store from several threads searching at once without locking.
+//
+// The replacement scheme prefers to keep entries that were searched to a greater depth, but will always replace entries
+// left over from previous searches.
+type TranspositionTable struct {
+	entries []ttSlot
+	mask    uint64
+	age     uint8 // age is only changed between searches, so it doesn't need to be accessed atomically.
+}
+
+// NewTranspositionTable returns a new transposition table that uses at most the given number of megabytes.
+func NewTranspositionTable(megabytes int) *TranspositionTable {
+	tt := &TranspositionTable{}
+	tt.Resize(megabytes)
+
+	return tt
+}
+
+// Resize changes the size of the transposition table to use at most the given number of megabytes. This clears all
+// entries in the table.
+func (tt *TranspositionTable) Resize(megabytes int) {
+	if megabytes < MinHashSize {
+		megabytes = MinHashSize
+	} else if megabytes > MaxHashSize {
+		megabytes = MaxHashSize
+	}
+
+	// The number of entries is rounded down to a power of two so that the index can be found with a mask rather than a
+	// modulo.
+	count := uint64(megabytes) * 1024 * 1024 / uint64(unsafe.Sizeof(ttSlot{}))
+	size := uint64(1)
+
+	for size*2 <= count {
+		size *= 2
+	}
+
+	tt.entries = make([]ttSlot, size)
+	tt.mask = size - 1
+	tt.age = 0
+}
+
+// Clear removes all entries from the transposition table.
+func (tt *TranspositionTable) Clear() {
+	for i := range tt.entries {
+		tt.entries[i] = ttSlot{}
+	}
+
+	tt.age = 0
+}
+
+// NewSearch increments the age of the table so that entries from previous searches are replaced first.
+func (tt *TranspositionTable) NewSearch() {
+	tt.age++
+}
+
+// Probe looks up the entry for the given hash, returning false if there is no entry for it.
+func (tt *TranspositionTable) Probe(hash uint64) (ttEntry, bool) {
+	entry := tt.load(hash & tt.mask)
+
+	if entry.bound == BoundNone || entry.key != hash {
+		return ttEntry{}, false
+	}
+
+	return entry, true
+}
+
+// Store records the result of searching a position. The score should be relative to the root, and is converted to be
+// relative to the position being stored using the ply.
+func (tt *TranspositionTable) Store(hash uint64, depth uint, ply int, score int16, bound Bound, move position.Move) {
+	index := hash & tt.mask
+	entry := tt.load(index)
+
+	// Keep entries from this search that were searched deeper than the one we are being asked to store, unless they are
+	// for the same position in which case the newer result is better.
+	if entry.key != hash && entry.age == tt.age && entry.bound != BoundNone && uint(entry.depth) > depth {
+		return
+	}
+
+	// Don't throw away a best move we already know about for this position if we don't have a new one.
+	if move == position.NoMove && entry.key == hash {
+		move = entry.move
+	}
+
+	if depth > 255 {
+		depth = 255
+	}
+
+	move.SetEval(0)
+
+	tt.save(index, ttEntry{
+		key:   hash,
+		move:  move,
+		score: scoreToTT(score, ply),
+		depth: uint8(depth),
+		bound: bound,
+		age:   tt.age,
+	})
+}
+
+// load reads the entry at the given index. An entry that was torn by two threads writing at once has the wrong key.
+func (tt *TranspositionTable) load(index uint64) ttEntry {
+	slot := &tt.entries[index]
+
+	check := atomic.LoadUint64(&slot.check)
+	move := atomic.LoadUint64(&slot.move)
+	data := atomic.LoadUint64(&slot.data)
+
+	return ttEntry{
+		key:   check ^ move ^ data,
+		move:  position.Move(move),
+		score: int16(uint16(data)),
+		depth: uint8(data >> 16),
+		bound: Bound(data >> 24),
+		age:   uint8(data >> 32),
+	}
+}
+
+// save writes an entry at the given index.
+func (tt *TranspositionTable) save(index uint64, entry ttEntry) {
+	slot := &tt.entries[index]
+
+	move := uint64(entry.move)
+	data := uint64(uint16(entry.score)) | uint64(entry.depth)<<16 | uint64(entry.bound)<<24 | uint64(entry.age)<<32
+
+	atomic.StoreUint64(&slot.check, entry.key^move^data)
+	atomic.StoreUint64(&slot.move, move)
+	atomic.StoreUint64(&slot.data, data)
+}
+
+// Hashfull returns how full the table is in permill, counting only entries from the current search. This is the
+// format expected by the "hashfull" field of the UCI "info" command.
+func (tt *TranspositionTable) Hashfull() int {
+	sample := 1000
+	if len(tt.entries) < sample {
+		sample = len(tt.entries)
+	}
+
+	used := 0
+	for i := 0; i < sample; i++ {
+		if entry := tt.load(uint64(i)); entry.bound != BoundNone && entry.age == tt.age {
+			used++
+		}
+	}
+
+	return used * 1000 / sample
+}
+
+// mateThreshold is the score beyond which a score is treated as a forced mate rather than an evaluation.
+const mateThreshold = position.MaxEval - maxPly
+
+// scoreToTT converts a mate score relative to the root into one relative to the current position, so that the same
+// entry gives the right distance to mate wherever the position is reached from.
+func scoreToTT(score int16, ply int) int16 {
+	if score > mateThreshold {
+		return score + int16(ply)
+	} else if score < -mateThreshold {
+		return score - int16(ply)
+	}
+
+	return score
+}
+
+// scoreFromTT converts a mate score stored in the transposition table back into one relative to the root.
+func scoreFromTT(score int16, ply int) int16 {
+	if score > mateThreshold {
+		return score - int16(ply)
+	} else if score < -mateThreshold {
+		return score + int16(ply)
+	}
+
+	return score
+}
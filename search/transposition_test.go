@@ -0,0 +1,174 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/ollybritton/StupidChess/position"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTranspositionTableStoreProbe tests that each kind of bound is stored and probed back unchanged, that the move is
+// stored without its evaluation, and that a missing or different position isn't found.
+func TestTranspositionTableStoreProbe(t *testing.T) {
+	tt := NewTranspositionTable(MinHashSize)
+
+	pos, err := position.NewPositionFromFEN(position.StartingPosition)
+	assert.NoError(t, err)
+
+	move := pos.MovesLegal().Moves[0]
+	move.SetEval(7)
+
+	_, ok := tt.Probe(pos.Hash())
+	assert.False(t, ok)
+
+	for _, bound := range []Bound{BoundExact, BoundLower, BoundUpper} {
+		tt.Store(pos.Hash(), 4, 0, -3, bound, move)
+
+		entry, ok := tt.Probe(pos.Hash())
+		assert.True(t, ok)
+		assert.Equal(t, pos.Hash(), entry.key)
+		assert.True(t, entry.move.Equal(move))
+		assert.Equal(t, int16(0), entry.move.Eval())
+		assert.Equal(t, int16(-3), entry.score)
+		assert.Equal(t, uint8(4), entry.depth)
+		assert.Equal(t, bound, entry.bound)
+	}
+
+	// A position that maps to the same slot isn't mistaken for the stored one.
+	_, ok = tt.Probe(pos.Hash() ^ (tt.mask + 1))
+	assert.False(t, ok)
+
+	// Storing without a move keeps the move already known for the position.
+	tt.Store(pos.Hash(), 5, 0, 1, BoundUpper, position.NoMove)
+	entry, _ := tt.Probe(pos.Hash())
+	assert.True(t, entry.move.Equal(move))
+
+	// Depths too large for an entry are capped rather than wrapping around.
+	tt.Store(pos.Hash(), 300, 0, 1, BoundExact, move)
+	entry, _ = tt.Probe(pos.Hash())
+	assert.Equal(t, uint8(255), entry.depth)
+}
+
+// TestTranspositionTableReplacement tests that a deeper entry from the current search is kept when a different position
+// maps to the same slot, but that entries from previous searches and for the same position are always replaced.
+func TestTranspositionTableReplacement(t *testing.T) {
+	tt := NewTranspositionTable(MinHashSize)
+	first, second := uint64(5), uint64(5+tt.mask+1)
+
+	tt.Store(first, 6, 0, 2, BoundExact, position.NoMove)
+	tt.Store(second, 3, 0, 1, BoundExact, position.NoMove)
+
+	_, ok := tt.Probe(first)
+	assert.True(t, ok, "deeper entry from the same search replaced")
+
+	_, ok = tt.Probe(second)
+	assert.False(t, ok)
+
+	// A shallower result for the same position is newer, so it replaces the old one.
+	tt.Store(first, 2, 0, 4, BoundLower, position.NoMove)
+	entry, ok := tt.Probe(first)
+	assert.True(t, ok)
+	assert.Equal(t, uint8(2), entry.depth)
+	assert.Equal(t, int16(4), entry.score)
+
+	tt.Store(first, 6, 0, 2, BoundExact, position.NoMove)
+	tt.NewSearch()
+	tt.Store(second, 1, 0, 1, BoundExact, position.NoMove)
+
+	_, ok = tt.Probe(first)
+	assert.False(t, ok, "entry from a previous search kept")
+
+	_, ok = tt.Probe(second)
+	assert.True(t, ok)
+}
+
+// TestTranspositionTableMateScores tests that mate scores are stored relative to the position and converted back
+// relative to the root at whatever ply the position is reached, while other scores are left alone.
+func TestTranspositionTableMateScores(t *testing.T) {
+	mating := position.MaxEval - 10 // Mate at ply 9 from the root.
+	mated := matedScore(8)          // Mated at ply 8 from the root.
+
+	tests := []struct {
+		score     int16
+		storePly  int
+		probePly  int
+		wantStore int16
+		wantProbe int16
+	}{
+		{mating, 5, 5, position.MaxEval - 5, mating},
+		{mating, 5, 3, position.MaxEval - 5, mating + 2},
+		{mating, 5, 7, position.MaxEval - 5, mating - 2},
+		{mated, 4, 4, mated - 4, mated},
+		{mated, 4, 2, mated - 4, mated - 2},
+		{mated, 4, 6, mated - 4, mated + 2},
+		{3, 5, 1, 3, 3},
+		{-3, 5, 9, -3, -3},
+	}
+
+	for _, test := range tests {
+		stored := scoreToTT(test.score, test.storePly)
+		assert.Equal(t, test.wantStore, stored, "storing %d at ply %d", test.score, test.storePly)
+		assert.Equal(t, test.wantProbe, scoreFromTT(stored, test.probePly), "probing %d at ply %d", test.score, test.probePly)
+	}
+
+	tt := NewTranspositionTable(MinHashSize)
+	tt.Store(1, 4, 5, mating, BoundExact, position.NoMove)
+
+	entry, ok := tt.Probe(1)
+	assert.True(t, ok)
+	assert.Equal(t, mating+2, scoreFromTT(entry.score, 3))
+}
+
+// TestTranspositionTableHashfull tests that hashfull counts the entries from the current search, and goes back to zero
+// when the table is cleared or resized.
+func TestTranspositionTableHashfull(t *testing.T) {
+	tt := NewTranspositionTable(MinHashSize)
+	assert.Equal(t, 0, tt.Hashfull())
+
+	for hash := uint64(0); hash < 500; hash++ {
+		tt.Store(hash, 1, 0, 0, BoundExact, position.NoMove)
+	}
+
+	assert.Equal(t, 500, tt.Hashfull())
+
+	tt.NewSearch()
+	assert.Equal(t, 0, tt.Hashfull(), "entries from a previous search counted")
+
+	for hash := uint64(0); hash < 1000; hash++ {
+		tt.Store(hash, 1, 0, 0, BoundExact, position.NoMove)
+	}
+
+	assert.Equal(t, 1000, tt.Hashfull())
+
+	tt.Clear()
+	assert.Equal(t, 0, tt.Hashfull())
+
+	tt.Store(0, 1, 0, 0, BoundExact, position.NoMove)
+	tt.Resize(2)
+	assert.Equal(t, 0, tt.Hashfull())
+
+	_, ok := tt.Probe(0)
+	assert.False(t, ok)
+}
+
+// TestHashOption tests that the Hash option resizes the table and rejects sizes out of range.
+func TestHashOption(t *testing.T) {
+	s := NewAlphaBetaSearch(make(chan Request), make(chan string), position.EvalSimple, position.EvalSimple)
+
+	assert.NoError(t, s.SetOption("Hash", "1"))
+	small := len(s.tt.entries)
+
+	assert.NoError(t, s.SetOption("Hash", "4"))
+	assert.Equal(t, 4*small, len(s.tt.entries))
+
+	assert.Error(t, s.SetOption("Hash", "0"))
+	assert.Error(t, s.SetOption("Hash", "4097"))
+	assert.Error(t, s.SetOption("Hash", "lots"))
+	assert.Equal(t, 4*small, len(s.tt.entries))
+
+	s.tt.Store(1, 1, 0, 0, BoundExact, position.NoMove)
+	assert.NoError(t, s.SetOption("Clear Hash", ""))
+
+	_, ok := s.tt.Probe(1)
+	assert.False(t, ok)
+}
@@ -2,12 +2,15 @@ package uci
 
 import (
 	"fmt"
+	"io"
 	"math/rand"
+	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/ollybritton/StupidChess/engines"
+	"github.com/ollybritton/StupidChess/pgn"
 	"github.com/ollybritton/StupidChess/position"
 	"github.com/ollybritton/StupidChess/search"
 )
@@ -19,8 +22,13 @@ type EngineSession struct {
 	engine    engines.Engine
 	positions []*position.Position
 	moves     []position.Move
+	chess960  bool // chess960 is set by the UCI_Chess960 option, and means castling is written as the king capturing its own rook.
 }
 
+// chess960Option is the option the GUI uses to tell the engine it is playing Chess960. It is handled by the session
+// rather than the engine, since it only changes how positions and moves are read and written.
+var chess960Option = search.Option{Name: "UCI_Chess960", Type: search.OptionCheck, Default: "false"}
+
 func NewEngineSession(eng engines.Engine) *EngineSession {
 	return &EngineSession{
 		engine:    eng,
@@ -56,6 +64,8 @@ func (s *EngineSession) Handle(commandLine string) error {
 		handler = s.handleCommandStop
 	case "ucinewgame":
 		handler = s.handleCommandNewGame
+	case "setoption":
+		handler = s.handleCommandSetOption
 
 	// Special debugging commands not in the UCI protocol
 	case "_pp", "_prettyprint":
@@ -91,6 +101,9 @@ func (s *EngineSession) Handle(commandLine string) error {
 	case "_ev", "_evaluate":
 		handler = s.handleCommandEvaluate
 
+	case "_pgn", "_loadpgn":
+		handler = s.handleCommandLoadPGN
+
 	// Handle unknown commands
 	default:
 		fmt.Printf("info string don't understand %s\n", commandName)
@@ -104,7 +117,11 @@ func (s *EngineSession) handleCommandUci(arguments []string) error {
 	fmt.Printf("id name %s\n", s.engine.Name())
 	fmt.Printf("id author %s\n", s.engine.Author())
 
-	// TODO: implement options being printed out
+	for _, option := range s.engine.Options() {
+		fmt.Println(option.String())
+	}
+
+	fmt.Println(chess960Option.String())
 
 	seed := time.Now().Unix()
 	fmt.Println("info string rng seed", seed)
@@ -163,14 +180,25 @@ func (s *EngineSession) handleCommandPosition(arguments []string) error {
 
 	}
 
+	// Searching the last position after being sent one that can't be played would give a move for the wrong position,
+	// so if the FEN string or any of the moves can't be used it is forgotten and "go" reports the error instead. The
+	// position is only kept once every move has been made on it.
 	pos, err := position.NewPositionFromFEN(fen)
 	if err != nil {
+		s.positions = nil
 		return fmt.Errorf("invalid position command sent %q, can't parse FEN: %w", strings.Join(arguments, " "), err)
 	}
 
+	// Chess960 positions are recognised from the FEN string, except those where the king and rooks start on the same
+	// squares as in standard chess.
+	if s.chess960 {
+		pos.Chess960 = true
+	}
+
 	for _, move := range moves {
-		parsed, err := position.ParseMove(move)
+		parsed, err := pos.ParseMove(move)
 		if err != nil {
+			s.positions = nil
 			return fmt.Errorf("invalid position command sent %q, can't understand move %q: %w", strings.Join(arguments, " "), move, err)
 		}
 
@@ -182,9 +210,34 @@ func (s *EngineSession) handleCommandPosition(arguments []string) error {
 	return nil
 }
 
+// goKeywords are the keywords that can follow the "go" command. These are needed to know where the list of moves given
+// with "searchmoves" ends.
+var goKeywords = map[string]bool{
+	"searchmoves": true,
+	"ponder":      true,
+	"wtime":       true,
+	"btime":       true,
+	"winc":        true,
+	"binc":        true,
+	"movestogo":   true,
+	"depth":       true,
+	"nodes":       true,
+	"mate":        true,
+	"movetime":    true,
+	"infinite":    true,
+}
+
 func (s *EngineSession) handleCommandGo(arguments []string) error {
 	options := search.NewDeafultOptions()
 
+	length := len(s.positions)
+
+	if length == 0 {
+		return fmt.Errorf("no positions to analyse")
+	}
+
+	pos := s.positions[length-1]
+
 	var i int
 
 	for i < len(arguments) {
@@ -194,6 +247,18 @@ func (s *EngineSession) handleCommandGo(arguments []string) error {
 		case "infinite":
 			options.Infinite = true
 
+		case "searchmoves":
+			for i+1 < len(arguments) && !goKeywords[arguments[i+1]] {
+				i++
+
+				move, err := pos.ParseMove(arguments[i])
+				if err != nil {
+					return fmt.Errorf("invalid move in 'searchmoves' option in 'go' command 'go %s': %w", strings.Join(arguments, " "), err)
+				}
+
+				options.SearchMoves = append(options.SearchMoves, move)
+			}
+
 		case "wtime", "btime":
 			if i == len(arguments)-1 {
 				return fmt.Errorf("expecting number after 'wtime/btime' option in 'go' command 'go %s'", strings.Join(arguments, " "))
@@ -308,14 +373,7 @@ func (s *EngineSession) handleCommandGo(arguments []string) error {
 		i++
 	}
 
-	length := len(s.positions)
-
-	if length == 0 {
-		return fmt.Errorf("no positions to analyse")
-	}
-
-	position := s.positions[length-1]
-	err := s.engine.Go(position, options)
+	err := s.engine.Go(pos, options)
 	if err != nil {
 		return fmt.Errorf("got an error searching for a move, %s", err)
 	}
@@ -339,6 +397,50 @@ func (s *EngineSession) handleCommandNewGame(arguments []string) error {
 	return nil
 }
 
+// handleCommandSetOption is called when the GUI gives the "setoption" command.
+// The setoption command in the UCI protocol has the following format:
+//
+//	setoption name <id> [value <x>]
+//
+// Both the name and the value can contain spaces, e.g. "setoption name Clear Hash".
+func (s *EngineSession) handleCommandSetOption(arguments []string) error {
+	if len(arguments) < 2 || arguments[0] != "name" {
+		return fmt.Errorf("invalid setoption command sent: %q", strings.Join(arguments, " "))
+	}
+
+	var name, value []string
+	var inValue bool
+
+	for _, field := range arguments[1:] {
+		if field == "value" && !inValue {
+			inValue = true
+			continue
+		}
+
+		if inValue {
+			value = append(value, field)
+		} else {
+			name = append(name, field)
+		}
+	}
+
+	if len(name) == 0 {
+		return fmt.Errorf("invalid setoption command sent, no option name: %q", strings.Join(arguments, " "))
+	}
+
+	if strings.EqualFold(strings.Join(name, " "), chess960Option.Name) {
+		enabled, err := strconv.ParseBool(strings.Join(value, " "))
+		if err != nil {
+			return fmt.Errorf("expecting true or false for option %q, got %q: %w", chess960Option.Name, strings.Join(value, " "), err)
+		}
+
+		s.chess960 = enabled
+		return nil
+	}
+
+	return s.engine.SetOption(strings.Join(name, " "), strings.Join(value, " "))
+}
+
 func (s *EngineSession) handleCommandUnknown(arguments []string) error {
 	return nil
 }
@@ -356,6 +458,10 @@ func (s *EngineSession) handleCommandPrettyPrint(arguments []string) error {
 }
 
 func (s *EngineSession) handleCommandBitboards(arguments []string) error {
+	if len(arguments) > 0 && position.SquareToString(position.StringToSquare(arguments[0])) != arguments[0] {
+		return fmt.Errorf("invalid square %q", arguments[0])
+	}
+
 	if len(s.positions) == 0 {
 		fmt.Println("nothing to pretty print, no positions yet")
 	} else {
@@ -393,6 +499,37 @@ func (s *EngineSession) handleCommandBitboards(arguments []string) error {
 		fmt.Println("KINGS:")
 		fmt.Println(curr.Pieces[position.King].String())
 		fmt.Println("")
+
+		whiteAttacks := curr.AttacksBy(position.White)
+		fmt.Println("WHITE attacks:")
+		fmt.Println(whiteAttacks.String())
+		fmt.Println("")
+
+		blackAttacks := curr.AttacksBy(position.Black)
+		fmt.Println("BLACK attacks:")
+		fmt.Println(blackAttacks.String())
+		fmt.Println("")
+
+		checkers := curr.Checkers()
+		fmt.Println("CHECKERS:")
+		fmt.Println(checkers.String())
+		fmt.Println("")
+
+		// With a square, also show what attacks it and what the piece on it attacks.
+		if len(arguments) > 0 {
+			square := position.StringToSquare(arguments[0])
+			occupied := curr.Occupied[position.White] | curr.Occupied[position.Black]
+
+			attackers := curr.AttackersTo(square, occupied)
+			fmt.Printf("ATTACKERS of %s:\n", arguments[0])
+			fmt.Println(attackers.StringWithMark(square))
+			fmt.Println("")
+
+			attacks := curr.AttacksFrom(square)
+			fmt.Printf("ATTACKS from %s:\n", arguments[0])
+			fmt.Println(attacks.StringWithMark(square))
+			fmt.Println("")
+		}
 	}
 
 	return nil
@@ -428,16 +565,22 @@ func (s *EngineSession) handleCommandLegalMoves(arguments []string) error {
 		return fmt.Errorf("no positions to analyse")
 	}
 
-	full := false
+	full, san := false, false
 	if len(arguments) == 1 && arguments[0] == "full" {
 		full = true
+	} else if len(arguments) == 1 && arguments[0] == "san" {
+		san = true
 	}
 
-	for i, move := range s.positions[length-1].MovesLegal().AsSlice() {
-		if !full {
-			fmt.Println(move.String())
-		} else {
+	pos := s.positions[length-1]
+
+	for i, move := range pos.MovesLegal().AsSlice() {
+		if full {
 			fmt.Printf("(%d) %s\n", i+1, move.FullString())
+		} else if san {
+			fmt.Printf("%s %s\n", move.String(), pos.StringSAN(move))
+		} else {
+			fmt.Println(move.String())
 		}
 	}
 
@@ -480,24 +623,12 @@ func (s *EngineSession) handleCommandMakeMove(arguments []string) error {
 	currPosition := s.positions[length-1]
 
 	for _, moveStr := range arguments {
-		// TODO: Defining the move twice like this is a bit iffy, just doing it like this because the parse move
-		// function doesn't know anything about the position. Maybe parseMove should take a position.
-		incompleteMove, err := position.ParseMove(moveStr)
+		move, err := currPosition.ParseMove(moveStr)
 		if err != nil {
 			return err
 		}
 
-		move := position.NewMove(
-			incompleteMove.From(),
-			incompleteMove.To(),
-			currPosition.Squares[incompleteMove.From()],
-			currPosition.Squares[incompleteMove.To()],
-			incompleteMove.Promotion(),
-			currPosition.Castling,
-			currPosition.EnPassant,
-		)
-
-		s.positions[length-1].MakeMove(move)
+		currPosition.MakeMove(move)
 		s.moves = append(s.moves, move)
 	}
 
@@ -565,8 +696,8 @@ func (s *EngineSession) handleCommandUndoMove(arguments []string) error {
 		return fmt.Errorf("no position to analyse")
 	}
 
-	move := s.moves[movesLength-1]
-	s.positions[positionsLength-1].UndoMove(move)
+	s.positions[positionsLength-1].Unmake()
+	s.moves = s.moves[:movesLength-1]
 
 	return nil
 }
@@ -592,3 +723,58 @@ func (s *EngineSession) handleCommandEvaluate(arguments []string) error {
 
 	return nil
 }
+
+// handleCommandLoadPGN loads the position at the end of a game in a PGN file, e.g.
+//
+//	_pgn games.pgn 3
+//
+// loads the third game in games.pgn. If no game number is given, the first game is used.
+func (s *EngineSession) handleCommandLoadPGN(arguments []string) error {
+	if len(arguments) == 0 {
+		return fmt.Errorf("expecting path to pgn file")
+	}
+
+	number := 1
+	if len(arguments) > 1 {
+		var err error
+		number, err = strconv.Atoi(arguments[1])
+		if err != nil || number < 1 {
+			return fmt.Errorf("invalid game number %q", arguments[1])
+		}
+	}
+
+	f, err := os.Open(arguments[0])
+	if err != nil {
+		return fmt.Errorf("couldn't open pgn file: %w", err)
+	}
+	defer f.Close()
+
+	reader := pgn.NewReader(f)
+
+	for i := 1; ; i++ {
+		game, err := reader.Next()
+		if err == io.EOF {
+			return fmt.Errorf("pgn file %s only has %d games", arguments[0], i-1)
+		}
+
+		if i < number {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		pos, err := game.Position()
+		if err != nil {
+			return err
+		}
+
+		s.positions = append(s.positions, pos)
+		for _, move := range game.Moves {
+			s.moves = append(s.moves, move.Move)
+		}
+
+		return nil
+	}
+}
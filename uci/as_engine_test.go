@@ -0,0 +1,58 @@
+package uci
+
+import (
+	"testing"
+
+	"github.com/ollybritton/StupidChess/position"
+	"github.com/ollybritton/StupidChess/search"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingEngine is an engine that records the positions it is asked to search instead of searching them.
+type recordingEngine struct {
+	searched []string // searched is the FEN string of every position passed to Go.
+}
+
+func (e *recordingEngine) Name() string                       { return "recording" }
+func (e *recordingEngine) Author() string                     { return "" }
+func (e *recordingEngine) NewGame() error                     { return nil }
+func (e *recordingEngine) Prepare() error                     { return nil }
+func (e *recordingEngine) Stop()                              {}
+func (e *recordingEngine) Options() []search.Option           { return nil }
+func (e *recordingEngine) SetOption(name, value string) error { return nil }
+
+func (e *recordingEngine) Go(pos *position.Position, options search.SearchOptions) error {
+	e.searched = append(e.searched, pos.StringFEN())
+	return nil
+}
+
+// TestHandleCommandPositionInvalid tests that after a position command with an illegal move or a bad FEN string, "go"
+// reports an error rather than searching the position from before it.
+func TestHandleCommandPositionInvalid(t *testing.T) {
+	commands := []string{
+		"position startpos moves e2e4 e7e5 e1e3",
+		"position startpos moves e2e4 e7e5 nonsense",
+		"position fen 8/8/8/8/8/8/8/8 w - - 0 1",
+	}
+
+	for _, command := range commands {
+		engine := &recordingEngine{}
+		s := NewEngineSession(engine)
+
+		assert.NoError(t, s.Handle("position startpos moves e2e4"))
+		assert.NoError(t, s.Handle("go depth 1"))
+		assert.Len(t, engine.searched, 1)
+
+		assert.Error(t, s.Handle(command))
+		assert.Error(t, s.Handle("go depth 1"), "after %q", command)
+		assert.Len(t, engine.searched, 1, "searched the previous position after %q", command)
+
+		// A valid position afterwards can be searched as normal.
+		assert.NoError(t, s.Handle("position startpos moves e2e4 e7e5"))
+		assert.NoError(t, s.Handle("go depth 1"))
+
+		if assert.Len(t, engine.searched, 2) {
+			assert.Equal(t, "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2", engine.searched[1])
+		}
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 
 	"github.com/ollybritton/StupidChess/position"
@@ -26,6 +28,10 @@ type GUISession struct {
 	command *exec.Cmd
 	stopped bool
 
+	position *position.Position // position is the last position loaded, which moves from the engine are played in.
+	chess960 bool               // chess960 is true if the engine has been told to write castling as in Chess960.
+	output   io.Writer          // output is where info and other messages from the engine are written.
+
 	moves  chan position.Move
 	errors chan error
 }
@@ -34,6 +40,7 @@ func NewGUISession(in io.Writer, out io.Reader) *GUISession {
 	return &GUISession{
 		in:     in,
 		out:    out,
+		output: os.Stdout,
 		moves:  make(chan position.Move),
 		errors: make(chan error),
 	}
@@ -69,6 +76,7 @@ func NewGUISessionFromBinary(path string, args ...string) (*GUISession, error) {
 		in:      stdin,
 		out:     stdout,
 		command: command,
+		output:  os.Stdout,
 		moves:   make(chan position.Move),
 		errors:  make(chan error),
 	}, nil
@@ -95,14 +103,18 @@ func (s GUISession) handleCommand(line string) error {
 
 	switch command {
 	case "info":
-		fmt.Println(line)
+		fmt.Fprintln(s.output, line)
 
 	case "bestmove":
 		if len(args) == 0 {
 			return fmt.Errorf("bestmove command '%s' invalid", line)
 		}
 
-		move, err := position.ParseMove(args[0])
+		if s.position == nil {
+			return fmt.Errorf("bestmove command '%s' sent before a position was loaded", line)
+		}
+
+		move, err := s.position.ParseMove(args[0])
 		if err != nil {
 			return fmt.Errorf("bestmove command '%s' invalid: %w", line, err)
 		}
@@ -110,7 +122,7 @@ func (s GUISession) handleCommand(line string) error {
 		s.moves <- move
 
 	default:
-		fmt.Println("got other", line)
+		fmt.Fprintln(s.output, "got other", line)
 	}
 
 	return nil
@@ -163,12 +175,51 @@ func (s *GUISession) Close() error {
 	return nil
 }
 
+// SetOutput changes where info and other messages from the engine are written, which is os.Stdout by default.
+func (s *GUISession) SetOutput(w io.Writer) {
+	s.output = w
+}
+
+// NewGame tells the engine that the next position is from a different game.
+func (s *GUISession) NewGame() error {
+	return s.sendCommand("ucinewgame")
+}
+
+// SetOption changes one of the options the engine exposes.
+func (s *GUISession) SetOption(name, value string) error {
+	return s.sendCommand("setoption name %s value %s", name, value)
+}
+
+// LoadPosition sets the position the engine searches from. If the position is from a game of Chess960, the engine is
+// told to write castling moves as the king capturing its own rook.
 func (s *GUISession) LoadPosition(pos *position.Position) {
-	s.sendCommand("position %s", pos.StringFEN())
+	if pos.Chess960 != s.chess960 {
+		s.chess960 = pos.Chess960
+		s.SetOption("UCI_Chess960", strconv.FormatBool(pos.Chess960))
+	}
+
+	s.position = pos.Copy()
+	s.sendCommand("position fen %s", pos.StringFEN())
 }
 
-func (s *GUISession) Go(options search.SearchOptions) position.Move {
-	s.sendCommand("go " + options.AsUCI())
-	move := <-s.moves
-	return move
+// Go starts the engine searching the last position loaded and waits for its best move.
+func (s *GUISession) Go(options search.SearchOptions) (position.Move, error) {
+	// Engines have no move to give when there are no legal moves, so they aren't asked for one.
+	if s.position != nil {
+		if outcome := s.position.Outcome(); outcome.Reason == position.ReasonCheckmate || outcome.Reason == position.ReasonStalemate {
+			return position.NoMove, fmt.Errorf("can't search position %s, the game is over: %s", s.position.StringFEN(), outcome)
+		}
+	}
+
+	err := s.sendCommand("go " + options.AsUCI())
+	if err != nil {
+		return position.NoMove, err
+	}
+
+	select {
+	case move := <-s.moves:
+		return move, nil
+	case err := <-s.errors:
+		return position.NoMove, err
+	}
 }